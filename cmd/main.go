@@ -5,13 +5,22 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
 	"github.com/iotassss/gizzmd/internal/handler"
+	"github.com/iotassss/gizzmd/internal/mailer"
+	"github.com/iotassss/gizzmd/internal/markdown"
 	"github.com/iotassss/gizzmd/internal/middleware"
+	"github.com/iotassss/gizzmd/internal/password"
 	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/search/bleveindex"
+	"github.com/iotassss/gizzmd/internal/sso"
+	"github.com/iotassss/gizzmd/internal/token"
+	"github.com/iotassss/gizzmd/internal/trash"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -73,20 +82,170 @@ func main() {
 		slog.Error("failed to connect to database", slog.Any("error", err))
 		return
 	}
+	// doc_links・doc_headingsテーブルを追加したときは、既存のドキュメントの本文からリンク・目次を作成する
+	rebuildDocLinks := !db.Migrator().HasTable(&gormrepo.DocLinkModel{})
+	rebuildDocHeadings := !db.Migrator().HasTable(&gormrepo.DocHeadingModel{})
 	err = db.AutoMigrate(
 		&gormrepo.UserModel{},
+		&gormrepo.WorkspaceModel{},
+		&gormrepo.WorkspaceMemberModel{},
 		&gormrepo.DocModel{},
+		&gormrepo.TagModel{},
+		&gormrepo.DocTagModel{},
+		&gormrepo.DocLinkModel{},
+		&gormrepo.DocHeadingModel{},
+		&gormrepo.DocRevisionModel{},
+		&gormrepo.DocPermissionModel{},
+		&gormrepo.ShareLinkModel{},
+		&gormrepo.EmailVerificationTokenModel{},
+		&gormrepo.PasswordResetTokenModel{},
+		&gormrepo.RefreshTokenModel{},
+		&gormrepo.RevokedAccessTokenModel{},
+		&gormrepo.PersonalAccessTokenModel{},
+		&gormrepo.ExternalIdentityModel{},
+		&gormrepo.OIDCLoginStateModel{},
+		&gormrepo.TOTPCredentialModel{},
+		&gormrepo.RecoveryCodeModel{},
+		&gormrepo.MFAChallengeModel{},
 	)
 	if err != nil {
 		slog.Error("failed to migrate database", slog.Any("error", err))
 		return
 	}
+	// カンマ区切りで保存していたタグをtags・doc_tagsテーブルに移す
+	if err := gormrepo.NewTagRepository(db, context.Background()).MigrateCSVTags(); err != nil {
+		slog.Error("failed to migrate document tags", slog.Any("error", err))
+		return
+	}
+
+	// password hasher
+	// ARGON2_* を変更すると、既存ユーザーのハッシュは次回ログイン時に再生成される
+	hasherParams := password.DefaultParams
+	if hasherParams.Memory, err = envUint32("ARGON2_MEMORY_KIB", hasherParams.Memory); err != nil {
+		slog.Error("invalid environment variable", slog.Any("error", err))
+		return
+	}
+	if hasherParams.Iterations, err = envUint32("ARGON2_ITERATIONS", hasherParams.Iterations); err != nil {
+		slog.Error("invalid environment variable", slog.Any("error", err))
+		return
+	}
+	parallelism, err := envUint32("ARGON2_PARALLELISM", uint32(hasherParams.Parallelism))
+	if err != nil || parallelism == 0 || parallelism > 255 {
+		slog.Error("invalid environment variable", slog.Any("error", "ARGON2_PARALLELISM"))
+		return
+	}
+	hasherParams.Parallelism = uint8(parallelism)
+	hasher := password.NewHasher(hasherParams)
+
+	// JWT signing keys
+	// JWT_KEYS は "kid:alg:path" のカンマ区切り。JWT_ACTIVE_KID の鍵で署名し、残りはローテーション前の旧鍵として検証のみに使う
+	jwtIssuer := os.Getenv("JWT_ISSUER")
+	if jwtIssuer == "" {
+		jwtIssuer = "gizzmd.com"
+	}
+	jwtAudience := os.Getenv("JWT_AUDIENCE")
+	if jwtAudience == "" {
+		jwtAudience = "gizzmd"
+	}
+	activeKID := os.Getenv("JWT_ACTIVE_KID")
+	var signingKeys []*token.Key
+	if keySpecs := os.Getenv("JWT_KEYS"); keySpecs != "" {
+		if signingKeys, err = token.ParseKeySpecs(keySpecs); err != nil {
+			slog.Error("failed to load JWT keys", slog.Any("error", err))
+			return
+		}
+		if activeKID == "" {
+			slog.Error("Missing required environment variables", slog.Any("error", "JWT_ACTIVE_KID"))
+			return
+		}
+	} else if env == "development" {
+		devKey, err := token.GenerateEd25519Key("dev-ephemeral")
+		if err != nil {
+			slog.Error("failed to generate JWT key", slog.Any("error", err))
+			return
+		}
+		slog.Warn("JWT_KEYS is not set; using an ephemeral signing key. tokens will be invalidated on restart")
+		signingKeys = []*token.Key{devKey}
+		activeKID = devKey.ID()
+	} else {
+		slog.Error("Missing required environment variables", slog.Any("error", "JWT_KEYS"))
+		return
+	}
+	keySet, err := token.NewKeySet(activeKID, signingKeys...)
+	if err != nil {
+		slog.Error("invalid JWT key configuration", slog.Any("error", err))
+		return
+	}
+	tokens := token.NewManager(keySet, token.Config{
+		Issuer:   jwtIssuer,
+		Audience: jwtAudience,
+	})
+
+	// OpenID Connect（任意）
+	// ローカルでは `go run ./cmd/mockoidc` のモックIdPに接続して動作確認できる
+	var oidcProvider *sso.Provider
+	if oidcIssuer := os.Getenv("OIDC_ISSUER"); oidcIssuer != "" {
+		oidcConfig := sso.Config{
+			Issuer:       oidcIssuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		}
+		if oidcConfig.ClientID == "" || oidcConfig.RedirectURL == "" {
+			slog.Error("Missing required environment variables", slog.Any("error", "OIDC_CLIENT_ID, OIDC_REDIRECT_URL"))
+			return
+		}
+		discoveryCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		oidcProvider, err = sso.NewProvider(discoveryCtx, oidcConfig)
+		cancel()
+		if err != nil {
+			slog.Error("failed to initialize oidc provider", slog.Any("error", err))
+			return
+		}
+	}
+
+	// mailer
+	appBaseURL := os.Getenv("APP_BASE_URL")
+	if appBaseURL == "" {
+		appBaseURL = "http://localhost:3000"
+	}
+	var mail mailer.Mailer
+	switch mailDriver := os.Getenv("MAIL_DRIVER"); mailDriver {
+	case "", "log":
+		mailLogPath := os.Getenv("MAIL_LOG_FILE")
+		if mailLogPath == "" {
+			mailLogPath = "mail.log"
+		}
+		mailLogFile, err := os.OpenFile(mailLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			slog.Error("failed to open mail log file", slog.Any("error", err))
+			return
+		}
+		defer mailLogFile.Close()
+		mail = mailer.NewLogMailer(mailLogFile)
+	case "smtp":
+		smtpConfig := mailer.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+		if smtpConfig.Host == "" || smtpConfig.Port == "" || smtpConfig.From == "" {
+			slog.Error("Missing required environment variables", slog.Any("error", "SMTP_HOST, SMTP_PORT, MAIL_FROM"))
+			return
+		}
+		mail = mailer.NewSMTPMailer(smtpConfig)
+	default:
+		slog.Error("invalid environment variable", slog.Any("error", "MAIL_DRIVER: "+mailDriver))
+		return
+	}
 
 	// dummy data
 	if env == "development" {
 		slog.Info("seeding dummy data")
 		userRepo := gormrepo.NewUserRepository(db, context.Background())
-		if err := userRepo.SeedDummyUser(); err != nil {
+		if err := userRepo.SeedDummyUser(hasher); err != nil {
 			slog.Error("failed to seed dummy data", slog.Any("error", err))
 			return
 		}
@@ -99,17 +258,148 @@ func main() {
 		slog.Info("dummy data seeded successfully")
 	}
 
+	// ワークスペース導入前のユーザー・ドキュメントを個人ワークスペースに割り当てる
+	if err := gormrepo.NewWorkspaceRepository(db, context.Background()).BackfillPersonalWorkspaces(); err != nil {
+		slog.Error("failed to backfill personal workspaces", slog.Any("error", err))
+		return
+	}
+	if rebuildDocLinks {
+		if err := gormrepo.NewDocLinkRepository(db, context.Background()).Rebuild(); err != nil {
+			slog.Error("failed to build document links", slog.Any("error", err))
+			return
+		}
+	}
+	if rebuildDocHeadings {
+		if err := gormrepo.NewDocRepository(db, context.Background()).RebuildHeadings(); err != nil {
+			slog.Error("failed to build document headings", slog.Any("error", err))
+			return
+		}
+	}
+
+	// `go run ./cmd backfill-snippets` で既存のドキュメントの概要を本文から作り直す
+	if len(os.Args) > 1 && os.Args[1] == "backfill-snippets" {
+		count, err := gormrepo.NewDocRepository(db, context.Background()).BackfillSnippets()
+		if err != nil {
+			slog.Error("failed to backfill snippets", slog.Any("error", err))
+			return
+		}
+		slog.Info("snippets backfilled", slog.Int("docs", count))
+		fmt.Printf("updated snippets of %d documents\n", count)
+		return
+	}
+
+	// search index
+	// SEARCH_BACKEND=bleve の場合は SEARCH_INDEX_PATH の検索インデックスで全文検索する。未設定の場合はMySQLの全文検索インデックスを使う
+	// `go run ./cmd reindex` でdocsテーブルから作り直す。インデックスは1つのプロセスからしか開けないため、サーバーを停止して実行する
+	reindex := len(os.Args) > 1 && os.Args[1] == "reindex"
+	var searchIndex domain.SearchIndex
+	switch searchBackend := os.Getenv("SEARCH_BACKEND"); searchBackend {
+	case "", "mysql":
+		if reindex {
+			slog.Error("reindex requires SEARCH_BACKEND=bleve")
+			return
+		}
+	case "bleve":
+		searchIndexPath := os.Getenv("SEARCH_INDEX_PATH")
+		if searchIndexPath == "" {
+			searchIndexPath = "search.bleve"
+		}
+		if reindex {
+			count, err := bleveindex.Rebuild(searchIndexPath, bleveindex.DefaultBoosts, gormrepo.NewDocRepository(db, context.Background()))
+			if err != nil {
+				slog.Error("failed to rebuild search index", slog.Any("error", err))
+				return
+			}
+			slog.Info("search index rebuilt", slog.String("path", searchIndexPath), slog.Int("docs", count))
+			fmt.Printf("indexed %d documents into %s\n", count, searchIndexPath)
+			return
+		}
+		index, err := bleveindex.Open(searchIndexPath, bleveindex.DefaultBoosts)
+		if err != nil {
+			slog.Error("failed to open search index", slog.Any("error", err))
+			return
+		}
+		defer index.Close()
+		searchIndex = index
+	default:
+		slog.Error("invalid environment variable", slog.Any("error", "SEARCH_BACKEND: "+searchBackend))
+		return
+	}
+
+	// trash retention
+	// TRASH_RETENTION_DAYS 日を過ぎたゴミ箱のドキュメントを完全に削除する。0の場合は自動削除しない
+	trashRetentionDays, err := envUint32("TRASH_RETENTION_DAYS", 30)
+	if err != nil {
+		slog.Error("invalid environment variable", slog.Any("error", err))
+		return
+	}
+	trashRetention := time.Duration(trashRetentionDays) * 24 * time.Hour
+	if trashRetentionDays > 0 {
+		go trash.NewPurger(db, trashRetention, time.Hour).Run(context.Background())
+	}
+
+	// Markdownの変換結果を本文ごとにメモリに保持する。合計64MBまでで、1MBを超える結果は保持しない
+	renderCache := markdown.NewCache(64<<20, 1<<20)
+
 	// handler
-	loginHandler := handler.NewLoginHandler(db)
+	loginHandler := handler.NewLoginHandler(db, hasher, tokens)
+	loginMFAHandler := handler.NewLoginMFAHandler(db, tokens)
+	refreshTokenHandler := handler.NewRefreshTokenHandler(db, tokens)
+	jwksHandler := handler.NewJWKSHandler(tokens)
+	logoutHandler := handler.NewLogoutHandler(db)
+	registerHandler := handler.NewRegisterHandler(db, hasher, mail, appBaseURL)
+	verifyEmailHandler := handler.NewVerifyEmailHandler(db)
+	resendVerificationHandler := handler.NewResendVerificationHandler(db, mail, appBaseURL)
+	forgotPasswordHandler := handler.NewForgotPasswordHandler(db, mail, appBaseURL)
+	resetPasswordHandler := handler.NewResetPasswordHandler(db, hasher)
 
-	docListHandler := handler.NewListDocsHandler(db)
-	docCreateHandler := handler.NewCreateDocHandler(db)
-	docGetHandler := handler.NewGetDocHandler(db)
-	docUpdateHandler := handler.NewUpdateDocHandler(db)
-	docDeleteHandler := handler.NewDeleteDocHandler(db)
+	docListHandler := handler.NewListDocsHandler(db, searchIndex)
+	docCreateHandler := handler.NewCreateDocHandler(db, searchIndex)
+	docGetHandler := handler.NewGetDocHandler(db, renderCache)
+	docUpdateHandler := handler.NewUpdateDocHandler(db, searchIndex)
+	docDeleteHandler := handler.NewDeleteDocHandler(db, searchIndex)
+	docMoveHandler := handler.NewMoveDocHandler(db)
+	docTreeHandler := handler.NewGetDocTreeHandler(db)
+	docOutlinkListHandler := handler.NewListDocOutlinksHandler(db)
+	docBacklinkListHandler := handler.NewListDocBacklinksHandler(db)
+	docSectionGetHandler := handler.NewGetDocSectionHandler(db)
+	docRevisionListHandler := handler.NewListDocRevisionsHandler(db)
+	docRevisionGetHandler := handler.NewGetDocRevisionHandler(db)
+	docRevisionDiffHandler := handler.NewDiffDocRevisionsHandler(db)
+	docRevisionRestoreHandler := handler.NewRestoreDocRevisionHandler(db, searchIndex)
+	trashListHandler := handler.NewListTrashHandler(db, trashRetention)
+	trashRestoreHandler := handler.NewRestoreTrashedDocHandler(db, searchIndex)
+	trashPurgeHandler := handler.NewPurgeTrashedDocHandler(db)
+	tagListHandler := handler.NewListTagsHandler(db)
+	tagRenameHandler := handler.NewRenameTagHandler(db, searchIndex)
+	tagMergeHandler := handler.NewMergeTagsHandler(db, searchIndex)
+	tagDeleteHandler := handler.NewDeleteTagHandler(db, searchIndex)
+	docPermissionListHandler := handler.NewListDocPermissionsHandler(db)
+	docPermissionGrantHandler := handler.NewGrantDocPermissionHandler(db)
+	docPermissionRevokeHandler := handler.NewRevokeDocPermissionHandler(db)
+	shareLinkListHandler := handler.NewListShareLinksHandler(db)
+	shareLinkCreateHandler := handler.NewCreateShareLinkHandler(db, hasher, appBaseURL)
+	shareLinkRevokeHandler := handler.NewRevokeShareLinkHandler(db)
+	sharedDocGetHandler := handler.NewGetSharedDocHandler(db, hasher, renderCache)
 
 	userGetHandler := handler.NewGetUserHandler(db)
 	userUpdateHandler := handler.NewUpdateUserHandler(db)
+	userChangePasswordHandler := handler.NewChangePasswordHandler(db, hasher)
+	userTokenListHandler := handler.NewListPersonalAccessTokensHandler(db)
+	userTokenCreateHandler := handler.NewCreatePersonalAccessTokenHandler(db)
+	userTokenRevokeHandler := handler.NewRevokePersonalAccessTokenHandler(db)
+	userMFAStatusHandler := handler.NewMFAStatusHandler(db)
+	userTOTPEnrollHandler := handler.NewEnrollTOTPHandler(db)
+	userTOTPEnableHandler := handler.NewEnableTOTPHandler(db)
+	userTOTPDisableHandler := handler.NewDisableTOTPHandler(db, hasher)
+	userRecoveryCodesHandler := handler.NewRegenerateRecoveryCodesHandler(db)
+
+	workspaceListHandler := handler.NewListWorkspacesHandler(db)
+	workspaceCreateHandler := handler.NewCreateWorkspaceHandler(db)
+	workspaceMemberListHandler := handler.NewListWorkspaceMembersHandler(db)
+	workspaceMemberAddHandler := handler.NewAddWorkspaceMemberHandler(db)
+	workspaceMemberRemoveHandler := handler.NewRemoveWorkspaceMemberHandler(db)
+	workspaceSwitchHandler := handler.NewSwitchWorkspaceHandler(db, tokens)
 
 	// router
 	r := gin.Default()
@@ -119,30 +409,99 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", handler.SharePasswordHeader},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	r.GET("/.well-known/jwks.json", jwksHandler)
+
 	// 認証不要なAPI
 	api := r.Group("/api")
 	{
 		api.POST("/login", loginHandler)
+		api.POST("/login/mfa", loginMFAHandler)
+		api.POST("/token/refresh", refreshTokenHandler)
+		api.POST("/register", registerHandler)
+		api.POST("/verify-email", verifyEmailHandler)
+		api.POST("/verify-email/resend", resendVerificationHandler)
+		api.POST("/password/forgot", forgotPasswordHandler)
+		api.POST("/password/reset", resetPasswordHandler)
+		api.GET("/shared/:token", sharedDocGetHandler)
+
+		if oidcProvider != nil {
+			api.GET("/oidc/login", handler.NewOIDCLoginHandler(db, oidcProvider))
+			api.GET("/oidc/callback", handler.NewOIDCCallbackHandler(db, oidcProvider, tokens))
+		}
 	}
 
 	// 認証が必要なAPI
 	authorized := r.Group("/api")
-	authorized.Use(middleware.AuthMiddleware())
+	authorized.Use(middleware.AuthMiddleware(db, tokens))
 	{
-		authorized.GET("/user", userGetHandler)
-		authorized.PATCH("/user", userUpdateHandler)
+		// パーソナルアクセストークンはスコープで、トークン管理などはログインセッションのみに制限する
+		session := middleware.RequireSession()
+		docsRead := middleware.RequireScope(domain.ScopeDocsRead)
+		docsWrite := middleware.RequireScope(domain.ScopeDocsWrite)
+		userRead := middleware.RequireScope(domain.ScopeUserRead)
+		userWrite := middleware.RequireScope(domain.ScopeUserWrite)
+
+		authorized.POST("/logout", session, logoutHandler)
+
+		authorized.GET("/user", userRead, userGetHandler)
+		authorized.PATCH("/user", userWrite, userUpdateHandler)
+		authorized.PUT("/user/password", session, userChangePasswordHandler)
+
+		authorized.GET("/user/tokens", session, userTokenListHandler)
+		authorized.POST("/user/tokens", session, userTokenCreateHandler)
+		authorized.DELETE("/user/tokens/:token_id", session, userTokenRevokeHandler)
+
+		authorized.GET("/user/mfa", session, userMFAStatusHandler)
+		authorized.POST("/user/mfa/totp", session, userTOTPEnrollHandler)
+		authorized.POST("/user/mfa/totp/enable", session, userTOTPEnableHandler)
+		authorized.DELETE("/user/mfa/totp", session, userTOTPDisableHandler)
+		authorized.POST("/user/mfa/recovery-codes", session, userRecoveryCodesHandler)
 
-		authorized.GET("/docs", docListHandler)
-		authorized.POST("/docs", docCreateHandler)
-		authorized.GET("/docs/:doc_id", docGetHandler)
-		authorized.PATCH("/docs/:doc_id", docUpdateHandler)
-		authorized.DELETE("/docs/:doc_id", docDeleteHandler)
+		authorized.GET("/workspaces", userRead, workspaceListHandler)
+		authorized.POST("/workspaces", userWrite, workspaceCreateHandler)
+		authorized.GET("/workspaces/:workspace_id/members", userRead, workspaceMemberListHandler)
+		authorized.POST("/workspaces/:workspace_id/members", userWrite, workspaceMemberAddHandler)
+		authorized.DELETE("/workspaces/:workspace_id/members/:user_id", userWrite, workspaceMemberRemoveHandler)
+		authorized.POST("/workspaces/:workspace_id/switch", session, workspaceSwitchHandler)
+
+		authorized.GET("/docs", docsRead, docListHandler)
+		authorized.POST("/docs", docsWrite, docCreateHandler)
+		authorized.GET("/docs/:doc_id", docsRead, docGetHandler)
+		authorized.PATCH("/docs/:doc_id", docsWrite, docUpdateHandler)
+		authorized.DELETE("/docs/:doc_id", docsWrite, docDeleteHandler)
+		authorized.POST("/docs/:doc_id/move", docsWrite, docMoveHandler)
+		authorized.GET("/docs/:doc_id/outlinks", docsRead, docOutlinkListHandler)
+		authorized.GET("/docs/:doc_id/backlinks", docsRead, docBacklinkListHandler)
+		authorized.GET("/docs/:doc_id/sections/:anchor", docsRead, docSectionGetHandler)
+		authorized.GET("/tree", docsRead, docTreeHandler)
+
+		authorized.GET("/docs/:doc_id/revisions", docsRead, docRevisionListHandler)
+		authorized.GET("/docs/:doc_id/revisions/:rev_id", docsRead, docRevisionGetHandler)
+		authorized.GET("/docs/:doc_id/revisions/:rev_id/diff", docsRead, docRevisionDiffHandler)
+		authorized.POST("/docs/:doc_id/revisions/:rev_id/restore", docsWrite, docRevisionRestoreHandler)
+
+		authorized.GET("/docs/:doc_id/permissions", docsRead, docPermissionListHandler)
+		authorized.POST("/docs/:doc_id/permissions", docsWrite, docPermissionGrantHandler)
+		authorized.DELETE("/docs/:doc_id/permissions/:user_id", docsWrite, docPermissionRevokeHandler)
+
+		authorized.GET("/docs/:doc_id/share-links", docsRead, shareLinkListHandler)
+		authorized.POST("/docs/:doc_id/share-links", docsWrite, shareLinkCreateHandler)
+		authorized.DELETE("/docs/:doc_id/share-links/:link_id", docsWrite, shareLinkRevokeHandler)
+
+		authorized.GET("/tags", docsRead, tagListHandler)
+		authorized.POST("/tags/merge", docsWrite, tagMergeHandler)
+		authorized.PATCH("/tags/:name", docsWrite, tagRenameHandler)
+		authorized.DELETE("/tags/:name", docsWrite, tagDeleteHandler)
+
+		authorized.GET("/trash", docsRead, trashListHandler)
+		authorized.POST("/trash/:doc_id/restore", docsWrite, trashRestoreHandler)
+		authorized.DELETE("/trash/:doc_id", docsWrite, trashPurgeHandler)
 	}
 
 	// // 静的ファイル（画像やsvgなど）を個別に配信
@@ -156,3 +515,16 @@ func main() {
 
 	r.Run() // デフォルトで :8080 で起動
 }
+
+// 環境変数を数値として読み込む。未設定の場合はdefを返す
+func envUint32(name string, def uint32) (uint32, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return uint32(n), nil
+}
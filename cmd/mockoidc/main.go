@@ -0,0 +1,234 @@
+// mockoidcはローカル検証用のOpenID Connectプロバイダーです。
+// 認可リクエストを画面なしで即座に承認し、MOCK_OIDC_EMAIL（またはlogin_hint）のユーザーとしてIDトークンを発行します。
+// 本番環境では使用しないでください。
+//
+//	go run ./cmd/mockoidc
+//	OIDC_ISSUER=http://localhost:9400 OIDC_CLIENT_ID=gizzmd OIDC_CLIENT_SECRET=secret \
+//	OIDC_REDIRECT_URL=http://localhost:8080/api/oidc/callback go run ./cmd
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"github.com/iotassss/gizzmd/internal/token"
+)
+
+const codeTTL = time.Minute
+
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	email         string
+	expiresAt     time.Time
+}
+
+type server struct {
+	issuer        string
+	clientID      string
+	clientSecret  string
+	defaultEmail  string
+	emailVerified bool
+	tokens        *token.Manager
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+func main() {
+	issuer := envOr("MOCK_OIDC_ISSUER", "http://localhost:9400")
+	u, err := url.Parse(issuer)
+	if err != nil {
+		slog.Error("invalid MOCK_OIDC_ISSUER", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	clientID := envOr("MOCK_OIDC_CLIENT_ID", "gizzmd")
+	key, err := token.GenerateEd25519Key("mockoidc")
+	if err != nil {
+		slog.Error("failed to generate key", slog.Any("error", err))
+		os.Exit(1)
+	}
+	keySet, err := token.NewKeySet(key.ID(), key)
+	if err != nil {
+		slog.Error("failed to build key set", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	s := &server{
+		issuer:        strings.TrimSuffix(issuer, "/"),
+		clientID:      clientID,
+		clientSecret:  envOr("MOCK_OIDC_CLIENT_SECRET", "secret"),
+		defaultEmail:  envOr("MOCK_OIDC_EMAIL", "oidc-user@example.com"),
+		emailVerified: os.Getenv("MOCK_OIDC_EMAIL_VERIFIED") != "false",
+		tokens:        token.NewManager(keySet, token.Config{Issuer: strings.TrimSuffix(issuer, "/"), Audience: clientID}),
+		codes:         make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+
+	addr := ":" + envOr("MOCK_OIDC_PORT", u.Port())
+	slog.Info("mock oidc provider listening", slog.String("issuer", s.issuer), slog.String("addr", addr))
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("server stopped", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{token.AlgEdDSA},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.tokens.JWKS())
+}
+
+// 認可リクエストを即座に承認し、認可コードを付けてredirect_uriへ戻す
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != s.clientID {
+		http.Error(w, "invalid response_type or client_id", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	email := q.Get("login_hint")
+	if email == "" {
+		email = s.defaultEmail
+	}
+	code, _, err := securetoken.Generate()
+	if err != nil {
+		http.Error(w, "failed to generate code", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.codes[code] = authorization{
+		clientID:      q.Get("client_id"),
+		redirectURI:   redirectURI.String(),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		email:         email,
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.clientSecret)) != 1 {
+		writeTokenError(w, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	auth, found := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+	if !found || time.Now().After(auth.expiresAt) || auth.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	name, _, _ := strings.Cut(auth.email, "@")
+	idToken, _, err := s.tokens.Sign(jwt.MapClaims{
+		"sub":            "mock|" + auth.email,
+		"nonce":          auth.nonce,
+		"email":          auth.email,
+		"email_verified": s.emailVerified,
+		"name":           name,
+	}, 5*time.Minute)
+	if err != nil {
+		writeTokenError(w, "server_error")
+		return
+	}
+	accessToken, _, err := securetoken.Generate()
+	if err != nil {
+		writeTokenError(w, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
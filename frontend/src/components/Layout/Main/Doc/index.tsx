@@ -73,11 +73,11 @@ const Doc: React.FC = () => {
         <div className="flex flex-wrap gap-4 text-sm text-gray-600 mb-4">
           <span>Created: {new Date(document.created_at).toLocaleDateString()}</span>
           <span>Updated: {new Date(document.edited_at).toLocaleDateString()}</span>
-          {document.tags && (
-            <span className="bg-blue-100 text-blue-800 px-2 py-1 rounded-full">
-              {document.tags}
+          {document.tags.map((tag) => (
+            <span key={tag} className="bg-blue-100 text-blue-800 px-2 py-1 rounded-full">
+              {tag}
             </span>
-          )}
+          ))}
         </div>
       </div>
       <div className="bg-white border border-gray-200 rounded-lg p-6">
@@ -3,6 +3,7 @@ import { useParams, useNavigate, useLocation } from "react-router-dom";
 import { useDocument } from "../../../../hooks/useDocument";
 import { useUpdateDocument } from "../../../../hooks/useUpdateDocument";
 import { MarkdownRenderer } from "../../../../utils/markdownRenderer";
+import { formatTags, parseTags } from "../../../../utils/tags";
 
 const DocEdit: React.FC = () => {
   const { uuid } = useParams<{ uuid: string }>();
@@ -14,12 +15,14 @@ const DocEdit: React.FC = () => {
   const [title, setTitle] = useState('');
   const [content, setContent] = useState('');
   const [tags, setTags] = useState('');
+  const [version, setVersion] = useState(0);
   const [hasChanges, setHasChanges] = useState(false);
   const [showPreview, setShowPreview] = useState(false);
   const [searchUuid, setSearchUuid] = useState(uuid || '');
 
   useEffect(() => {
     if (document) {
+      setVersion(document.version);
       // Check if we have editing content from location state (coming from preview)
       const editingContent = location.state?.editingContent;
       if (editingContent) {
@@ -29,7 +32,7 @@ const DocEdit: React.FC = () => {
       } else {
         setTitle(document.title);
         setContent(document.content);
-        setTags(document.tags);
+        setTags(formatTags(document.tags));
       }
     }
   }, [document, location.state]);
@@ -43,18 +46,22 @@ const DocEdit: React.FC = () => {
       const changed =
         title !== document.title ||
         content !== document.content ||
-        tags !== document.tags;
+        formatTags(parseTags(tags)) !== formatTags(document.tags);
       setHasChanges(changed);
     }
   }, [title, content, tags, document]);
 
   const saveOnly = async () => {
     if (!uuid || !hasChanges) return;
-    await updateDocument(uuid, {
+    const updated = await updateDocument(uuid, {
       title: title.trim(),
       content,
-      tags: tags.trim(),
+      tags: parseTags(tags),
+      version,
     });
+    if (updated) {
+      setVersion(updated.version);
+    }
   };
 
   const handleSave = async () => {
@@ -73,7 +80,7 @@ const DocEdit: React.FC = () => {
 
     window.addEventListener('keydown', handleKeyDown);
     return () => window.removeEventListener('keydown', handleKeyDown);
-  }, [uuid, hasChanges, title, content, tags]);
+  }, [uuid, hasChanges, title, content, tags, version]);
 
   const handleCancel = () => {
     navigate(`/doc/${uuid}`);
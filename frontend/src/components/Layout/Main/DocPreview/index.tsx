@@ -2,6 +2,7 @@ import React, { useState, useEffect } from "react";
 import { useParams, useNavigate, useLocation } from "react-router-dom";
 import { useDocument } from "../../../../hooks/useDocument";
 import { MarkdownRenderer } from "../../../../utils/markdownRenderer";
+import { formatTags, parseTags } from "../../../../utils/tags";
 
 const DocPreview: React.FC = () => {
   const { uuid } = useParams<{ uuid: string }>();
@@ -21,7 +22,7 @@ const DocPreview: React.FC = () => {
   const hasUnsavedChanges = editingContent && document && (
     editingContent.title !== document.title ||
     editingContent.content !== document.content ||
-    editingContent.tags !== document.tags
+    formatTags(parseTags(editingContent.tags)) !== formatTags(document.tags)
   );
 
   const handleSearch = (e: React.FormEvent) => {
@@ -131,7 +132,7 @@ const DocPreview: React.FC = () => {
   const displayContent = editingContent || {
     title: document?.title || '',
     content: document?.content || '',
-    tags: document?.tags || '',
+    tags: formatTags(document?.tags || []),
     created_at: document?.created_at || '',
     edited_at: document?.edited_at || ''
   };
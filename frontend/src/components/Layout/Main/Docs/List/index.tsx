@@ -71,14 +71,14 @@ const List: React.FC<ListProps> = ({ documents, loading, error }) => {
             {doc.preview}
           </p>
 
-          {doc.tags && (
+          {doc.tags.length > 0 && (
             <div className="flex flex-wrap gap-1">
-              {doc.tags.split(',').filter(tag => tag.trim()).map((tag, index) => (
+              {doc.tags.map((tag, index) => (
                 <span
                   key={index}
                   className="inline-block bg-blue-100 text-blue-800 text-xs px-2 py-1 rounded-full"
                 >
-                  {tag.trim()}
+                  {tag}
                 </span>
               ))}
             </div>
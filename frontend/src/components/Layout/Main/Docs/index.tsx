@@ -64,7 +64,7 @@ const Docs: React.FC = () => {
     const result = await createDocument({
       title,
       content: '',
-      tags: '',
+      tags: [],
     });
 
     if (result) {
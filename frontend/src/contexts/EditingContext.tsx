@@ -1,10 +1,11 @@
 import React, { createContext, useContext, useState } from 'react';
 import type { ReactNode } from 'react';
+import { formatTags, parseTags } from '../utils/tags';
 
 interface EditingContent {
   title: string;
   content: string;
-  tags: string;
+  tags: string; // 入力欄のカンマ区切り文字列
   created_at?: string;
   edited_at?: string;
 }
@@ -55,7 +56,7 @@ export const EditingProvider: React.FC<EditingProviderProps> = ({ children }) =>
     return (
       editing.title !== originalDocument.title ||
       editing.content !== originalDocument.content ||
-      editing.tags !== originalDocument.tags
+      formatTags(parseTags(editing.tags)) !== formatTags(originalDocument.tags ?? [])
     );
   };
 
@@ -5,7 +5,7 @@ import type { Document } from '../types/document';
 interface CreateDocumentRequest {
   title: string;
   content: string;
-  tags: string;
+  tags: string[];
 }
 
 export const useCreateDocument = () => {
@@ -5,7 +5,8 @@ import type { Document } from '../types/document';
 interface UpdateDocumentRequest {
   title?: string;
   content?: string;
-  tags?: string;
+  tags?: string[];
+  version: number;
 }
 
 export const useUpdateDocument = () => {
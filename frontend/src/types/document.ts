@@ -2,7 +2,7 @@ export interface DocumentSummary {
   id: string;
   title: string;
   preview: string;
-  tags: string;
+  tags: string[];
   created_at: string;
   updated_at: string;
 }
@@ -37,9 +37,19 @@ export interface Document {
   id: string;
   title: string;
   content: string;
-  tags: string;
+  tags: string[];
   snippet: string;
   author_id: string;
   created_at: string;
   edited_at: string;
+  version: number;
+  parent_id: string | null;
+  position: number;
+  breadcrumbs?: DocumentBreadcrumb[];
+  toc?: DocumentHeading[];
+}
+
+export interface DocumentBreadcrumb {
+  id: string;
+  title: string;
 }
\ No newline at end of file
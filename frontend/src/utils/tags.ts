@@ -0,0 +1,5 @@
+// タグの入力欄はカンマ区切りの文字列で編集し、APIとは配列でやり取りする
+export const formatTags = (tags: string[]): string => tags.join(', ');
+
+export const parseTags = (input: string): string[] =>
+  input.split(',').map(tag => tag.trim()).filter(tag => tag);
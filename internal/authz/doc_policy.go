@@ -0,0 +1,85 @@
+package authz
+
+import (
+	"errors"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+)
+
+// ドキュメントに対する操作
+type Action string
+
+const (
+	ActionRead              Action = "read"
+	ActionUpdate            Action = "update"
+	ActionDelete            Action = "delete"
+	ActionManagePermissions Action = "manage_permissions"
+)
+
+// DocPolicyはユーザーがドキュメントを操作できるかを判定する
+// ハンドラーはリポジトリから取得したドキュメントを必ずこのポリシーに通してから返す・変更する
+type DocPolicy struct {
+	permissions domain.DocPermissionRepository
+	members     domain.WorkspaceMemberRepository
+}
+
+func NewDocPolicy(permissions domain.DocPermissionRepository, members domain.WorkspaceMemberRepository) *DocPolicy {
+	return &DocPolicy{
+		permissions: permissions,
+		members:     members,
+	}
+}
+
+// RoleOfはユーザーのドキュメントに対する権限を返す
+// 作成者は常にowner。それ以外はワークスペースでの権限とドキュメント単位の共有のうち強い方で、どちらもなければゼロ値（何もできない）
+func (p *DocPolicy) RoleOf(userID domain.ID, doc domain.Doc) (domain.DocRole, error) {
+	if userID.IsNil() {
+		return domain.DocRole{}, nil
+	}
+	if doc.AuthorId() == userID {
+		return domain.OwnerDocRole(), nil
+	}
+
+	var role domain.DocRole
+	if !doc.WorkspaceID().IsNil() {
+		member, err := p.members.Find(doc.WorkspaceID(), userID)
+		if err == nil {
+			role = role.Max(member.Role().DocRole())
+		} else if !errors.Is(err, domain.ErrEntityNotFound) {
+			return domain.DocRole{}, err
+		}
+	}
+	permission, err := p.permissions.Find(doc.ID(), userID)
+	if err == nil {
+		role = role.Max(permission.Role())
+	} else if !errors.Is(err, domain.ErrEntityNotFound) {
+		return domain.DocRole{}, err
+	}
+	return role, nil
+}
+
+// Authorizeは操作を許可しない場合にエラーを返す
+// 閲覧できないドキュメントは存在自体を知られないようErrEntityNotFound、閲覧はできるが操作できない場合はErrForbiddenを返す
+func (p *DocPolicy) Authorize(userID domain.ID, doc domain.Doc, action Action) error {
+	role, err := p.RoleOf(userID, doc)
+	if err != nil {
+		return err
+	}
+	if !role.CanRead() {
+		return domain.ErrEntityNotFound
+	}
+
+	var allowed bool
+	switch action {
+	case ActionRead:
+		allowed = true
+	case ActionUpdate:
+		allowed = role.CanEdit()
+	case ActionDelete, ActionManagePermissions:
+		allowed = role.CanManage()
+	}
+	if !allowed {
+		return domain.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+// Package diff は2つのテキストの行単位の差分を unified diff 形式で出力する
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultContext は変更行の前後に出力する行数
+const DefaultContext = 3
+
+// 比較する行数と編集数の上限。探索の記録は編集数の2乗に比例してメモリを使うため、これを超える場合は比較しない
+const (
+	MaxLines = 100000
+	MaxEdits = 1000
+)
+
+// ErrTooLarge は行数か編集数が上限を超えていることを表す
+var ErrTooLarge = errors.New("diff: texts are too large or too different to compare")
+
+type op byte
+
+const (
+	opEqual  op = ' '
+	opDelete op = '-'
+	opInsert op = '+'
+)
+
+type edit struct {
+	op   op
+	line string
+}
+
+// Unified は from から to への差分を unified diff 形式で返す
+// 差分がない場合は空文字を返す。行数か編集数が上限を超える場合は ErrTooLarge を返す
+func Unified(fromName, toName, from, to string, context int) (string, error) {
+	fromLines, toLines := splitLines(from), splitLines(to)
+	if len(fromLines) > MaxLines || len(toLines) > MaxLines {
+		return "", ErrTooLarge
+	}
+	edits, ok := lineEdits(fromLines, toLines)
+	if !ok {
+		return "", ErrTooLarge
+	}
+	hunks := buildHunks(edits, context)
+	if len(hunks) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		h.write(&b)
+	}
+	return b.String(), nil
+}
+
+// 改行を含めて行に分割する。末尾の行は改行を含まない場合がある
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Myersのアルゴリズムで最短の編集手順を求める。編集数が MaxEdits を超える場合はfalse
+func lineEdits(a, b []string) ([]edit, bool) {
+	// 共通の先頭・末尾を除いてから比較し、探索範囲を狭める
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{opEqual, line})
+	}
+	middle, ok := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if !ok {
+		return nil, false
+	}
+	edits = append(edits, middle...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{opEqual, line})
+	}
+	return edits, true
+}
+
+func myers(a, b []string) ([]edit, bool) {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, true
+	}
+	limit := min(n+m, MaxEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	// trace[d] は d 回目の探索を始める時点の、対角線 k ∈ [-d, d] の到達位置
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrack(trace [][]int, a, b []string) []edit {
+	x, y := len(a), len(b)
+	var reversed []edit
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, edit{opEqual, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, edit{opInsert, b[y-1]})
+			y--
+		} else {
+			reversed = append(reversed, edit{opDelete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, edit{opEqual, a[x-1]})
+		x--
+		y--
+	}
+
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+type hunk struct {
+	fromStart, fromCount int
+	toStart, toCount     int
+	edits                []edit
+}
+
+// 変更箇所を前後context行とともにまとめる
+// 間の変更されていない行が2*context行以下の変更箇所は1つのhunkにする
+func buildHunks(edits []edit, context int) []hunk {
+	if context < 0 {
+		context = 0
+	}
+
+	var changes []int
+	for i, e := range edits {
+		if e.op != opEqual {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// 各編集の直前までに出現した行数
+	fromPos := make([]int, len(edits)+1)
+	toPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		fromPos[i+1], toPos[i+1] = fromPos[i], toPos[i]
+		if e.op != opInsert {
+			fromPos[i+1]++
+		}
+		if e.op != opDelete {
+			toPos[i+1]++
+		}
+	}
+
+	var hunks []hunk
+	for i := 0; i < len(changes); {
+		start := max(changes[i]-context, 0)
+		last := changes[i]
+		i++
+		for i < len(changes) && changes[i]-last <= 2*context+1 {
+			last = changes[i]
+			i++
+		}
+		end := min(last+context+1, len(edits))
+
+		h := hunk{
+			fromStart: fromPos[start] + 1,
+			fromCount: fromPos[end] - fromPos[start],
+			toStart:   toPos[start] + 1,
+			toCount:   toPos[end] - toPos[start],
+			edits:     edits[start:end],
+		}
+		// 行数が0の場合は、直前の行番号を示す
+		if h.fromCount == 0 {
+			h.fromStart--
+		}
+		if h.toCount == 0 {
+			h.toStart--
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+func (h hunk) write(b *strings.Builder) {
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(h.fromStart, h.fromCount), hunkRange(h.toStart, h.toCount))
+	for _, e := range h.edits {
+		b.WriteByte(byte(e.op))
+		b.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,347 @@
+// Package docquery はドキュメント一覧の検索クエリを解析し、検索条件に変換する
+//
+//	tag:API -tag:draft created:>=2025-01-01 author:me "error handling" 設計 OR 仕様
+//
+// 空白で区切った条件はすべて満たす必要があり（AND）、ORでつないだ条件はいずれかを満たせばよい。
+// ORはANDより先に結合する。先頭に-を付けた条件は否定になる
+package docquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+)
+
+const (
+	maxQueryLength = 500
+	dateLayout     = "2006-01-02"
+)
+
+const (
+	fieldTag     = "tag"
+	fieldAuthor  = "author"
+	fieldCreated = "created"
+	fieldUpdated = "updated"
+)
+
+// Errorはクエリの誤り。Positionは誤りのある箇所の1始まりの文字位置
+type Error struct {
+	Position int
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Position, e.Message)
+}
+
+// AuthorResolverはauthor:に指定されたme・ユーザーID以外の値（メールアドレス）をユーザーIDに変換する
+// 該当するユーザーがいない場合はdomain.ErrEntityNotFoundを返す
+type AuthorResolver func(value string) (domain.ID, error)
+
+type Result struct {
+	// ORや否定を含まない語・フレーズ。全文検索の関連度にも使うため、DocsQueryのSearchTextに含める
+	Text   string
+	Filter domain.DocFilter
+}
+
+// クエリを構成する語
+type term struct {
+	pos      int // -を含む語の先頭位置
+	negated  bool
+	field    string
+	fieldPos int
+	value    string
+	valuePos int
+	quoted   bool
+}
+
+func (t term) isOr() bool {
+	return !t.negated && !t.quoted && t.field == "" && t.value == "OR"
+}
+
+// Parseはクエリを解析する。author:meはviewerIDのユーザーとして扱う
+func Parse(input string, viewerID domain.ID, resolveAuthor AuthorResolver) (Result, error) {
+	runes := []rune(input)
+	if len(runes) > maxQueryLength {
+		return Result{}, &Error{Position: maxQueryLength + 1, Message: fmt.Sprintf("query cannot exceed %d characters", maxQueryLength)}
+	}
+	terms, err := lex(runes)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var groups [][]domain.DocCondition
+	var groupTerms [][]term
+	orPos := 0
+	for _, t := range terms {
+		if t.isOr() {
+			if len(groups) == 0 || orPos != 0 {
+				return Result{}, &Error{Position: t.pos, Message: "OR must be placed between two conditions"}
+			}
+			orPos = t.pos
+			continue
+		}
+
+		condition, err := compile(t, viewerID, resolveAuthor)
+		if err != nil {
+			return Result{}, err
+		}
+		if orPos != 0 {
+			last := len(groups) - 1
+			groups[last] = append(groups[last], condition)
+			groupTerms[last] = append(groupTerms[last], t)
+			orPos = 0
+			continue
+		}
+		groups = append(groups, []domain.DocCondition{condition})
+		groupTerms = append(groupTerms, []term{t})
+	}
+	if orPos != 0 {
+		return Result{}, &Error{Position: orPos, Message: "OR must be followed by a condition"}
+	}
+
+	// 単独の語・フレーズは全文検索の文字列に移し、それ以外を絞り込み条件にする
+	var text []string
+	var filter [][]domain.DocCondition
+	for i, group := range groups {
+		if len(group) == 1 && group[0].Field() == domain.DocConditionText && !group[0].Negated() {
+			if groupTerms[i][0].quoted {
+				text = append(text, `"`+group[0].Value()+`"`)
+			} else {
+				text = append(text, group[0].Value())
+			}
+			continue
+		}
+		filter = append(filter, group)
+	}
+
+	return Result{
+		Text:   strings.Join(text, " "),
+		Filter: domain.NewDocFilter(filter),
+	}, nil
+}
+
+func lex(runes []rune) ([]term, error) {
+	var terms []term
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		t := term{pos: i + 1}
+		if runes[i] == '-' {
+			if i+1 >= len(runes) || unicode.IsSpace(runes[i+1]) {
+				return nil, &Error{Position: i + 1, Message: "- must be followed by a condition"}
+			}
+			t.negated = true
+			i++
+		}
+
+		if runes[i] == '"' {
+			value, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			t.value, t.valuePos, t.quoted = value, i+1, true
+			i = next
+		} else {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+				i++
+			}
+			word := runes[start:i]
+			t.value, t.valuePos = string(word), start+1
+			if colon := indexRune(word, ':'); colon > 0 && isFieldName(word[:colon]) {
+				t.field, t.fieldPos = strings.ToLower(string(word[:colon])), start+1
+				t.value, t.valuePos = string(word[colon+1:]), start+colon+2
+				if i < len(runes) && runes[i] == '"' && t.value == "" {
+					value, next, err := readQuoted(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					t.value, t.valuePos, t.quoted = value, i+1, true
+					i = next
+				}
+			}
+			if i < len(runes) && runes[i] == '"' {
+				return nil, &Error{Position: i + 1, Message: "unexpected quote; put a space before a quoted phrase"}
+			}
+		}
+
+		if t.quoted && i < len(runes) && !unicode.IsSpace(runes[i]) {
+			return nil, &Error{Position: i + 1, Message: "expected a space after the closing quote"}
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// runes[start]の"から閉じる"までを読み、内側の文字列と閉じる"の次の位置を返す
+func readQuoted(runes []rune, start int) (string, int, error) {
+	end := start + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	if end >= len(runes) {
+		return "", 0, &Error{Position: start + 1, Message: "unterminated quote"}
+	}
+	value := strings.Join(strings.Fields(string(runes[start+1:end])), " ")
+	if value == "" {
+		return "", 0, &Error{Position: start + 1, Message: "quoted value is empty"}
+	}
+	return value, end + 1, nil
+}
+
+func indexRune(runes []rune, r rune) int {
+	for i := range runes {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// 英字のみの場合はフィールド名とみなす。12:30のような語は検索語として扱う
+func isFieldName(runes []rune) bool {
+	for _, r := range runes {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func compile(t term, viewerID domain.ID, resolveAuthor AuthorResolver) (domain.DocCondition, error) {
+	if t.field == "" {
+		return domain.NewTextCondition(t.value, t.negated), nil
+	}
+	if t.value == "" {
+		return domain.DocCondition{}, &Error{Position: t.valuePos, Message: fmt.Sprintf("%s: requires a value", t.field)}
+	}
+
+	switch t.field {
+	case fieldTag:
+		if err := domain.ValidateTagName(t.value); err != nil {
+			return domain.DocCondition{}, &Error{Position: t.valuePos, Message: err.Error()}
+		}
+		return domain.NewTagCondition(t.value, t.negated), nil
+
+	case fieldAuthor:
+		authorID, err := compileAuthor(t, viewerID, resolveAuthor)
+		if err != nil {
+			return domain.DocCondition{}, err
+		}
+		return domain.NewAuthorCondition(authorID, t.negated), nil
+
+	case fieldCreated, fieldUpdated:
+		from, to, err := parseDateRange(t.value, t.valuePos)
+		if err != nil {
+			return domain.DocCondition{}, err
+		}
+		if t.field == fieldCreated {
+			return domain.NewCreatedCondition(from, to, t.negated), nil
+		}
+		return domain.NewUpdatedCondition(from, to, t.negated), nil
+
+	default:
+		return domain.DocCondition{}, &Error{
+			Position: t.fieldPos,
+			Message:  fmt.Sprintf("unknown field %q; allowed fields are tag, author, created and updated (quote the term to search it as text)", t.field),
+		}
+	}
+}
+
+func compileAuthor(t term, viewerID domain.ID, resolveAuthor AuthorResolver) (domain.ID, error) {
+	if t.value == "me" {
+		return viewerID, nil
+	}
+	if id, err := domain.NewID(t.value); err == nil {
+		return id, nil
+	}
+	id, err := resolveAuthor(t.value)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			return domain.ID{}, &Error{Position: t.valuePos, Message: fmt.Sprintf("unknown author %q; use me, a user ID or an email address", t.value)}
+		}
+		return domain.ID{}, err
+	}
+	return id, nil
+}
+
+// 日付の条件をfrom以上to未満の範囲にする。日付はサーバーのタイムゾーンで解釈する
+//
+//	2025-01-01             その日
+//	>=2025-01-01 >2025-01-01 <=2025-01-01 <2025-01-01
+//	2025-01-01..2025-01-31 両端の日を含む。どちらかを省略できる
+func parseDateRange(value string, pos int) (*time.Time, *time.Time, error) {
+	if lower, upper, ok := strings.Cut(value, ".."); ok {
+		if lower == "" && upper == "" {
+			return nil, nil, &Error{Position: pos, Message: "date range requires at least one date"}
+		}
+		var from, to *time.Time
+		if lower != "" {
+			d, err := parseDate(lower, pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			from = &d
+		}
+		if upper != "" {
+			d, err := parseDate(upper, pos+utf8.RuneCountInString(lower)+2)
+			if err != nil {
+				return nil, nil, err
+			}
+			next := d.AddDate(0, 0, 1)
+			to = &next
+		}
+		if from != nil && to != nil && !from.Before(*to) {
+			return nil, nil, &Error{Position: pos, Message: "date range start must not be after its end"}
+		}
+		return from, to, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		rest, ok := strings.CutPrefix(value, op)
+		if !ok {
+			continue
+		}
+		d, err := parseDate(rest, pos+len(op))
+		if err != nil {
+			return nil, nil, err
+		}
+		next := d.AddDate(0, 0, 1)
+		switch op {
+		case ">=":
+			return &d, nil, nil
+		case ">":
+			return &next, nil, nil
+		case "<=":
+			return nil, &next, nil
+		case "<":
+			return nil, &d, nil
+		default:
+			return &d, &next, nil
+		}
+	}
+
+	d, err := parseDate(value, pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	next := d.AddDate(0, 0, 1)
+	return &d, &next, nil
+}
+
+func parseDate(value string, pos int) (time.Time, error) {
+	d, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, &Error{Position: pos, Message: fmt.Sprintf("invalid date %q; expected YYYY-MM-DD", value)}
+	}
+	return d, nil
+}
@@ -1,18 +1,24 @@
 package domain
 
 type Doc struct {
-	id        ID
-	title     DocTitle
-	content   Content
-	tags      Tags
-	snippet   DocSnippet
-	authorId  ID
-	createdAt CreatedAt
-	editedAt  EditedAt
+	id          ID
+	workspaceID ID
+	title       DocTitle
+	content     Content
+	tags        Tags
+	snippet     DocSnippet
+	authorId    ID
+	createdAt   CreatedAt
+	editedAt    EditedAt
+	editedBy    ID
+	version     int
+	parentID    ID
+	position    int
 }
 
 func NewDoc(
 	id ID,
+	workspaceID ID,
 	title DocTitle,
 	content Content,
 	tags Tags,
@@ -20,24 +26,72 @@ func NewDoc(
 	authorId ID,
 	createdAt CreatedAt,
 	editedAt EditedAt,
+	editedBy ID,
+	version int,
+	parentID ID,
+	position int,
 ) Doc {
 	return Doc{
-		id:        id,
-		title:     title,
-		content:   content,
-		tags:      tags,
-		snippet:   snippet,
-		authorId:  authorId,
-		createdAt: createdAt,
-		editedAt:  editedAt,
+		id:          id,
+		workspaceID: workspaceID,
+		title:       title,
+		content:     content,
+		tags:        tags,
+		snippet:     snippet,
+		authorId:    authorId,
+		createdAt:   createdAt,
+		editedAt:    editedAt,
+		editedBy:    editedBy,
+		version:     version,
+		parentID:    parentID,
+		position:    position,
 	}
 }
 
-func (d Doc) ID() ID              { return d.id }
-func (d Doc) Title() DocTitle     { return d.title }
-func (d Doc) Content() Content    { return d.content }
-func (d Doc) Tags() Tags          { return d.tags }
-func (d Doc) Snippet() DocSnippet { return d.snippet }
-func (d Doc) AuthorId() ID        { return d.authorId }
+func (d Doc) ID() ID               { return d.id }
+func (d Doc) WorkspaceID() ID      { return d.workspaceID }
+func (d Doc) Title() DocTitle      { return d.title }
+func (d Doc) Content() Content     { return d.content }
+func (d Doc) Tags() Tags           { return d.tags }
+func (d Doc) Snippet() DocSnippet  { return d.snippet }
+func (d Doc) AuthorId() ID         { return d.authorId }
 func (d Doc) CreatedAt() CreatedAt { return d.createdAt }
-func (d Doc) EditedAt() EditedAt  { return d.editedAt }
+func (d Doc) EditedAt() EditedAt   { return d.editedAt }
+
+// 最後に編集したユーザー。リビジョンの編集者として記録する
+func (d Doc) EditedBy() ID { return d.editedBy }
+
+// 保存のたびに1ずつ増える版数。未保存のドキュメントは0
+// 保存時に読み込んだ時点の版数と異なる場合は、他の更新と競合したものとして扱う
+func (d Doc) Version() int { return d.version }
+
+// 親のドキュメント。ワークスペースの最上位にある場合はゼロ値
+func (d Doc) ParentID() ID { return d.parentID }
+
+// 同じ親を持つドキュメントの中での並び順。0始まり
+func (d Doc) Position() int { return d.position }
+
+// MoveToはドキュメントをancestorsの末尾のドキュメントの子としてposition番目に移動したものを返す
+// ancestorsは移動先の親とその祖先をルートから順に並べたもの。空の場合はワークスペースの最上位に移動する
+// 自身や子孫の下には移動できず、ErrDocCycleを返す
+func (d Doc) MoveTo(ancestors []Doc, position int) (Doc, error) {
+	if position < 0 {
+		return Doc{}, ErrValidationFailed
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ID() == d.id {
+			return Doc{}, ErrDocCycle
+		}
+		if ancestor.WorkspaceID() != d.workspaceID {
+			return Doc{}, ErrValidationFailed
+		}
+	}
+
+	moved := d
+	moved.parentID = ID{}
+	if len(ancestors) > 0 {
+		moved.parentID = ancestors[len(ancestors)-1].ID()
+	}
+	moved.position = position
+	return moved, nil
+}
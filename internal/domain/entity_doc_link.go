@@ -0,0 +1,22 @@
+package domain
+
+// ドキュメント本文中の[[...]]形式のリンク
+type DocLink struct {
+	target string
+	doc    Doc
+}
+
+// docはリンク先。リンク切れの場合はゼロ値
+func NewDocLink(target string, doc Doc) DocLink {
+	return DocLink{
+		target: target,
+		doc:    doc,
+	}
+}
+
+// 本文中のリンク先の指定。タイトル、またはdoc:に続くドキュメントID
+func (l DocLink) Target() string { return l.target }
+func (l DocLink) Doc() Doc       { return l.doc }
+
+// リンク先のドキュメントが存在しないか、ゴミ箱にある
+func (l DocLink) IsBroken() bool { return l.doc.ID().IsNil() }
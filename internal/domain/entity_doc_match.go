@@ -0,0 +1,19 @@
+package domain
+
+// 検索条件に一致したドキュメント
+type DocMatch struct {
+	doc   Doc
+	score float64
+}
+
+func NewDocMatch(doc Doc, score float64) DocMatch {
+	return DocMatch{
+		doc:   doc,
+		score: score,
+	}
+}
+
+func (m DocMatch) Doc() Doc { return m.doc }
+
+// 全文検索の関連度。大きいほど関連が強い。全文検索をしない場合は0
+func (m DocMatch) Score() float64 { return m.score }
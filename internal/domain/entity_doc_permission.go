@@ -0,0 +1,33 @@
+package domain
+
+// ドキュメントを作成者以外のユーザーと共有するための権限
+// 作成者は権限を持たなくても常にownerとして扱う
+type DocPermission struct {
+	docID     ID
+	userID    ID
+	role      DocRole
+	grantedBy ID
+	createdAt CreatedAt
+}
+
+func NewDocPermission(
+	docID ID,
+	userID ID,
+	role DocRole,
+	grantedBy ID,
+	createdAt CreatedAt,
+) DocPermission {
+	return DocPermission{
+		docID:     docID,
+		userID:    userID,
+		role:      role,
+		grantedBy: grantedBy,
+		createdAt: createdAt,
+	}
+}
+
+func (p DocPermission) DocID() ID            { return p.docID }
+func (p DocPermission) UserID() ID           { return p.userID }
+func (p DocPermission) Role() DocRole        { return p.role }
+func (p DocPermission) GrantedBy() ID        { return p.grantedBy }
+func (p DocPermission) CreatedAt() CreatedAt { return p.createdAt }
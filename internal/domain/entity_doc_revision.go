@@ -0,0 +1,44 @@
+package domain
+
+// ドキュメント保存時点の内容を記録した、変更不可能な履歴
+type DocRevision struct {
+	id        ID
+	docID     ID
+	number    int // ドキュメントごとに1から始まる連番
+	title     DocTitle
+	content   Content
+	tags      Tags
+	editedBy  ID
+	createdAt CreatedAt
+}
+
+func NewDocRevision(
+	id ID,
+	docID ID,
+	number int,
+	title DocTitle,
+	content Content,
+	tags Tags,
+	editedBy ID,
+	createdAt CreatedAt,
+) DocRevision {
+	return DocRevision{
+		id:        id,
+		docID:     docID,
+		number:    number,
+		title:     title,
+		content:   content,
+		tags:      tags,
+		editedBy:  editedBy,
+		createdAt: createdAt,
+	}
+}
+
+func (r DocRevision) ID() ID               { return r.id }
+func (r DocRevision) DocID() ID            { return r.docID }
+func (r DocRevision) Number() int          { return r.number }
+func (r DocRevision) Title() DocTitle      { return r.title }
+func (r DocRevision) Content() Content     { return r.content }
+func (r DocRevision) Tags() Tags           { return r.tags }
+func (r DocRevision) EditedBy() ID         { return r.editedBy }
+func (r DocRevision) CreatedAt() CreatedAt { return r.createdAt }
@@ -0,0 +1,19 @@
+package domain
+
+// ドキュメントの階層を1段ずつ取得するときの要素
+type DocTreeNode struct {
+	doc         Doc
+	hasChildren bool
+}
+
+func NewDocTreeNode(doc Doc, hasChildren bool) DocTreeNode {
+	return DocTreeNode{
+		doc:         doc,
+		hasChildren: hasChildren,
+	}
+}
+
+func (n DocTreeNode) Doc() Doc { return n.doc }
+
+// ゴミ箱にない子のドキュメントがあるか。閲覧できない子も含む
+func (n DocTreeNode) HasChildren() bool { return n.hasChildren }
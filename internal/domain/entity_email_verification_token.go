@@ -0,0 +1,29 @@
+package domain
+
+// メールアドレス確認用のワンタイムトークン
+// 平文のトークンはメールでのみ送信し、永続化するのはハッシュ値のみ
+type EmailVerificationToken struct {
+	id        ID
+	userID    ID
+	tokenHash string
+	expiresAt ExpiresAt
+}
+
+func NewEmailVerificationToken(
+	id ID,
+	userID ID,
+	tokenHash string,
+	expiresAt ExpiresAt,
+) EmailVerificationToken {
+	return EmailVerificationToken{
+		id:        id,
+		userID:    userID,
+		tokenHash: tokenHash,
+		expiresAt: expiresAt,
+	}
+}
+
+func (t EmailVerificationToken) ID() ID               { return t.id }
+func (t EmailVerificationToken) UserID() ID           { return t.userID }
+func (t EmailVerificationToken) TokenHash() string    { return t.tokenHash }
+func (t EmailVerificationToken) ExpiresAt() ExpiresAt { return t.expiresAt }
@@ -0,0 +1,37 @@
+package domain
+
+// 外部IdP（OpenID Connect）のアカウントとUserの紐付け
+// issuerとsubjectの組でIdP上のアカウントを一意に識別する
+type ExternalIdentity struct {
+	id        ID
+	userID    ID
+	issuer    string
+	subject   string
+	email     Email
+	createdAt CreatedAt
+}
+
+func NewExternalIdentity(
+	id ID,
+	userID ID,
+	issuer string,
+	subject string,
+	email Email,
+	createdAt CreatedAt,
+) ExternalIdentity {
+	return ExternalIdentity{
+		id:        id,
+		userID:    userID,
+		issuer:    issuer,
+		subject:   subject,
+		email:     email,
+		createdAt: createdAt,
+	}
+}
+
+func (e ExternalIdentity) ID() ID               { return e.id }
+func (e ExternalIdentity) UserID() ID           { return e.userID }
+func (e ExternalIdentity) Issuer() string       { return e.issuer }
+func (e ExternalIdentity) Subject() string      { return e.subject }
+func (e ExternalIdentity) Email() Email         { return e.email }
+func (e ExternalIdentity) CreatedAt() CreatedAt { return e.createdAt }
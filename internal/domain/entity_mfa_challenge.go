@@ -0,0 +1,33 @@
+package domain
+
+// パスワード認証後、2要素目の入力を待っているログイン
+// 平文のトークンはログインレスポンスでのみ返し、永続化するのはハッシュ値のみ
+type MFAChallenge struct {
+	id        ID
+	userID    ID
+	tokenHash string
+	attempts  int
+	expiresAt ExpiresAt
+}
+
+func NewMFAChallenge(
+	id ID,
+	userID ID,
+	tokenHash string,
+	attempts int,
+	expiresAt ExpiresAt,
+) MFAChallenge {
+	return MFAChallenge{
+		id:        id,
+		userID:    userID,
+		tokenHash: tokenHash,
+		attempts:  attempts,
+		expiresAt: expiresAt,
+	}
+}
+
+func (c MFAChallenge) ID() ID               { return c.id }
+func (c MFAChallenge) UserID() ID           { return c.userID }
+func (c MFAChallenge) TokenHash() string    { return c.tokenHash }
+func (c MFAChallenge) Attempts() int        { return c.attempts }
+func (c MFAChallenge) ExpiresAt() ExpiresAt { return c.expiresAt }
@@ -0,0 +1,29 @@
+package domain
+
+// OpenID Connectの認可リクエスト中の状態
+// コールバックでstateを照合し、nonceとPKCEのcode_verifierを取り出す
+type OIDCLoginState struct {
+	stateHash    string
+	nonce        string
+	codeVerifier string
+	expiresAt    ExpiresAt
+}
+
+func NewOIDCLoginState(
+	stateHash string,
+	nonce string,
+	codeVerifier string,
+	expiresAt ExpiresAt,
+) OIDCLoginState {
+	return OIDCLoginState{
+		stateHash:    stateHash,
+		nonce:        nonce,
+		codeVerifier: codeVerifier,
+		expiresAt:    expiresAt,
+	}
+}
+
+func (s OIDCLoginState) StateHash() string    { return s.stateHash }
+func (s OIDCLoginState) Nonce() string        { return s.nonce }
+func (s OIDCLoginState) CodeVerifier() string { return s.codeVerifier }
+func (s OIDCLoginState) ExpiresAt() ExpiresAt { return s.expiresAt }
@@ -0,0 +1,29 @@
+package domain
+
+// パスワード再設定用のワンタイムトークン
+// 平文のトークンはメールでのみ送信し、永続化するのはハッシュ値のみ
+type PasswordResetToken struct {
+	id        ID
+	userID    ID
+	tokenHash string
+	expiresAt ExpiresAt
+}
+
+func NewPasswordResetToken(
+	id ID,
+	userID ID,
+	tokenHash string,
+	expiresAt ExpiresAt,
+) PasswordResetToken {
+	return PasswordResetToken{
+		id:        id,
+		userID:    userID,
+		tokenHash: tokenHash,
+		expiresAt: expiresAt,
+	}
+}
+
+func (t PasswordResetToken) ID() ID               { return t.id }
+func (t PasswordResetToken) UserID() ID           { return t.userID }
+func (t PasswordResetToken) TokenHash() string    { return t.tokenHash }
+func (t PasswordResetToken) ExpiresAt() ExpiresAt { return t.expiresAt }
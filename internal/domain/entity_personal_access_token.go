@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// スクリプトやCIから利用するパーソナルアクセストークン
+// 平文のトークンは作成時に一度だけ返し、永続化するのはハッシュ値のみ
+type PersonalAccessToken struct {
+	id         ID
+	userID     ID
+	name       TokenName
+	tokenHash  string
+	scopes     Scopes
+	expiresAt  ExpiresAt
+	lastUsedAt time.Time // ゼロ値は未使用
+	createdAt  CreatedAt
+}
+
+func NewPersonalAccessToken(
+	id ID,
+	userID ID,
+	name TokenName,
+	tokenHash string,
+	scopes Scopes,
+	expiresAt ExpiresAt,
+	lastUsedAt time.Time,
+	createdAt CreatedAt,
+) PersonalAccessToken {
+	return PersonalAccessToken{
+		id:         id,
+		userID:     userID,
+		name:       name,
+		tokenHash:  tokenHash,
+		scopes:     scopes,
+		expiresAt:  expiresAt,
+		lastUsedAt: lastUsedAt,
+		createdAt:  createdAt,
+	}
+}
+
+func (t PersonalAccessToken) ID() ID                { return t.id }
+func (t PersonalAccessToken) UserID() ID            { return t.userID }
+func (t PersonalAccessToken) Name() TokenName       { return t.name }
+func (t PersonalAccessToken) TokenHash() string     { return t.tokenHash }
+func (t PersonalAccessToken) Scopes() Scopes        { return t.scopes }
+func (t PersonalAccessToken) ExpiresAt() ExpiresAt  { return t.expiresAt }
+func (t PersonalAccessToken) LastUsedAt() time.Time { return t.lastUsedAt }
+func (t PersonalAccessToken) CreatedAt() CreatedAt  { return t.createdAt }
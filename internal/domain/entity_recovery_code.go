@@ -0,0 +1,25 @@
+package domain
+
+// 2段階認証のリカバリーコード
+// 認証アプリを使えない場合に一度だけ使用できる。永続化するのはハッシュ値のみ
+type RecoveryCode struct {
+	id       ID
+	userID   ID
+	codeHash string
+}
+
+func NewRecoveryCode(
+	id ID,
+	userID ID,
+	codeHash string,
+) RecoveryCode {
+	return RecoveryCode{
+		id:       id,
+		userID:   userID,
+		codeHash: codeHash,
+	}
+}
+
+func (c RecoveryCode) ID() ID           { return c.id }
+func (c RecoveryCode) UserID() ID       { return c.userID }
+func (c RecoveryCode) CodeHash() string { return c.codeHash }
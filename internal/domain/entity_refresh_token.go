@@ -0,0 +1,38 @@
+package domain
+
+// リフレッシュトークン
+// ローテーションのたびに同じfamilyIDで新しいトークンを発行する。
+// 失効済みのトークンが再利用された場合は漏洩とみなし、family全体を失効させる
+type RefreshToken struct {
+	id        ID
+	userID    ID
+	familyID  ID
+	tokenHash string
+	expiresAt ExpiresAt
+	revoked   bool
+}
+
+func NewRefreshToken(
+	id ID,
+	userID ID,
+	familyID ID,
+	tokenHash string,
+	expiresAt ExpiresAt,
+	revoked bool,
+) RefreshToken {
+	return RefreshToken{
+		id:        id,
+		userID:    userID,
+		familyID:  familyID,
+		tokenHash: tokenHash,
+		expiresAt: expiresAt,
+		revoked:   revoked,
+	}
+}
+
+func (t RefreshToken) ID() ID               { return t.id }
+func (t RefreshToken) UserID() ID           { return t.userID }
+func (t RefreshToken) FamilyID() ID         { return t.familyID }
+func (t RefreshToken) TokenHash() string    { return t.tokenHash }
+func (t RefreshToken) ExpiresAt() ExpiresAt { return t.expiresAt }
+func (t RefreshToken) Revoked() bool        { return t.revoked }
@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// アカウントを持たない相手にドキュメントを読み取り専用で公開するリンク
+// 平文のトークンは作成時にのみ返し、永続化するのはハッシュ値のみ
+type ShareLink struct {
+	id             ID
+	docID          ID
+	tokenHash      string
+	passwordHash   PasswordHash // 空の場合はパスワードなし
+	expiresAt      ExpiresAt    // ゼロ値の場合は無期限
+	createdBy      ID
+	accessCount    int
+	lastAccessedAt time.Time
+	// パスワードを続けて間違えた回数と、最後に間違えた日時
+	failedAttempts int
+	lastFailedAt   time.Time
+	createdAt      CreatedAt
+}
+
+func NewShareLink(
+	id ID,
+	docID ID,
+	tokenHash string,
+	passwordHash PasswordHash,
+	expiresAt ExpiresAt,
+	createdBy ID,
+	accessCount int,
+	lastAccessedAt time.Time,
+	failedAttempts int,
+	lastFailedAt time.Time,
+	createdAt CreatedAt,
+) ShareLink {
+	return ShareLink{
+		id:             id,
+		docID:          docID,
+		tokenHash:      tokenHash,
+		passwordHash:   passwordHash,
+		expiresAt:      expiresAt,
+		createdBy:      createdBy,
+		accessCount:    accessCount,
+		lastAccessedAt: lastAccessedAt,
+		failedAttempts: failedAttempts,
+		lastFailedAt:   lastFailedAt,
+		createdAt:      createdAt,
+	}
+}
+
+func (l ShareLink) ID() ID                     { return l.id }
+func (l ShareLink) DocID() ID                  { return l.docID }
+func (l ShareLink) TokenHash() string          { return l.tokenHash }
+func (l ShareLink) PasswordHash() PasswordHash { return l.passwordHash }
+func (l ShareLink) ExpiresAt() ExpiresAt       { return l.expiresAt }
+func (l ShareLink) CreatedBy() ID              { return l.createdBy }
+func (l ShareLink) AccessCount() int           { return l.accessCount }
+func (l ShareLink) LastAccessedAt() time.Time  { return l.lastAccessedAt }
+func (l ShareLink) FailedAttempts() int        { return l.failedAttempts }
+func (l ShareLink) LastFailedAt() time.Time    { return l.lastFailedAt }
+func (l ShareLink) CreatedAt() CreatedAt       { return l.createdAt }
+
+func (l ShareLink) IsPasswordProtected() bool { return !l.passwordHash.IsEmpty() }
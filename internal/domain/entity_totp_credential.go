@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// ユーザーのTOTP（認証アプリ）設定
+// 登録直後は無効で、認証アプリのコードを一度検証できた時点で有効になる
+type TOTPCredential struct {
+	userID       ID
+	secret       string
+	enabled      bool
+	lastUsedStep int64
+	// 2要素目の検証に続けて失敗した回数と、最後に失敗した日時
+	failedAttempts int
+	lastFailedAt   time.Time
+}
+
+func NewTOTPCredential(
+	userID ID,
+	secret string,
+	enabled bool,
+	lastUsedStep int64,
+	failedAttempts int,
+	lastFailedAt time.Time,
+) TOTPCredential {
+	return TOTPCredential{
+		userID:         userID,
+		secret:         secret,
+		enabled:        enabled,
+		lastUsedStep:   lastUsedStep,
+		failedAttempts: failedAttempts,
+		lastFailedAt:   lastFailedAt,
+	}
+}
+
+func (c TOTPCredential) UserID() ID     { return c.userID }
+func (c TOTPCredential) Secret() string { return c.secret }
+func (c TOTPCredential) Enabled() bool  { return c.enabled }
+
+// 最後に使用されたタイムステップ。これ以前のコードは再利用とみなす
+func (c TOTPCredential) LastUsedStep() int64 { return c.lastUsedStep }
+
+func (c TOTPCredential) FailedAttempts() int     { return c.failedAttempts }
+func (c TOTPCredential) LastFailedAt() time.Time { return c.lastFailedAt }
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// ゴミ箱に移動したドキュメント
+// 保持期間を過ぎると完全に削除される
+type TrashedDoc struct {
+	doc       Doc
+	deletedAt time.Time
+}
+
+func NewTrashedDoc(doc Doc, deletedAt time.Time) TrashedDoc {
+	return TrashedDoc{
+		doc:       doc,
+		deletedAt: deletedAt,
+	}
+}
+
+func (t TrashedDoc) Doc() Doc             { return t.doc }
+func (t TrashedDoc) DeletedAt() time.Time { return t.deletedAt }
@@ -1,10 +1,13 @@
 package domain
 
 type User struct {
-	id         ID
-	email      Email
-	authorName AuthorName
-	uiTheme    UITheme
+	id                ID
+	email             Email
+	authorName        AuthorName
+	uiTheme           UITheme
+	passwordHash      PasswordHash
+	emailVerified     bool
+	activeWorkspaceID ID
 }
 
 func NewUser(
@@ -12,16 +15,27 @@ func NewUser(
 	email Email,
 	authorName AuthorName,
 	uiTheme UITheme,
+	passwordHash PasswordHash,
+	emailVerified bool,
+	activeWorkspaceID ID,
 ) User {
 	return User{
-		id:         id,
-		email:      email,
-		authorName: authorName,
-		uiTheme:    uiTheme,
+		id:                id,
+		email:             email,
+		authorName:        authorName,
+		uiTheme:           uiTheme,
+		passwordHash:      passwordHash,
+		emailVerified:     emailVerified,
+		activeWorkspaceID: activeWorkspaceID,
 	}
 }
 
-func (u User) ID() ID                 { return u.id }
-func (u User) Email() Email           { return u.email }
-func (u User) AuthorName() AuthorName { return u.authorName }
-func (u User) UITheme() UITheme       { return u.uiTheme }
+func (u User) ID() ID                     { return u.id }
+func (u User) Email() Email               { return u.email }
+func (u User) AuthorName() AuthorName     { return u.authorName }
+func (u User) UITheme() UITheme           { return u.uiTheme }
+func (u User) PasswordHash() PasswordHash { return u.passwordHash }
+func (u User) EmailVerified() bool        { return u.emailVerified }
+
+// 最後に選択したワークスペース。ログイン時にJWTのwidクレームとして発行する
+func (u User) ActiveWorkspaceID() ID { return u.activeWorkspaceID }
@@ -0,0 +1,30 @@
+package domain
+
+// ドキュメントを所有する単位。メンバー以外からは見えない
+// ユーザーごとに、本人のみが所属する個人ワークスペースを1つ持つ
+type Workspace struct {
+	id              ID
+	name            WorkspaceName
+	personalOwnerID ID // 個人ワークスペースの場合のみ、その持ち主
+	createdAt       CreatedAt
+}
+
+func NewWorkspace(
+	id ID,
+	name WorkspaceName,
+	personalOwnerID ID,
+	createdAt CreatedAt,
+) Workspace {
+	return Workspace{
+		id:              id,
+		name:            name,
+		personalOwnerID: personalOwnerID,
+		createdAt:       createdAt,
+	}
+}
+
+func (w Workspace) ID() ID               { return w.id }
+func (w Workspace) Name() WorkspaceName  { return w.name }
+func (w Workspace) PersonalOwnerID() ID  { return w.personalOwnerID }
+func (w Workspace) CreatedAt() CreatedAt { return w.createdAt }
+func (w Workspace) IsPersonal() bool     { return !w.personalOwnerID.IsNil() }
@@ -0,0 +1,27 @@
+package domain
+
+type WorkspaceMember struct {
+	workspaceID ID
+	userID      ID
+	role        WorkspaceRole
+	joinedAt    CreatedAt
+}
+
+func NewWorkspaceMember(
+	workspaceID ID,
+	userID ID,
+	role WorkspaceRole,
+	joinedAt CreatedAt,
+) WorkspaceMember {
+	return WorkspaceMember{
+		workspaceID: workspaceID,
+		userID:      userID,
+		role:        role,
+		joinedAt:    joinedAt,
+	}
+}
+
+func (m WorkspaceMember) WorkspaceID() ID     { return m.workspaceID }
+func (m WorkspaceMember) UserID() ID          { return m.userID }
+func (m WorkspaceMember) Role() WorkspaceRole { return m.role }
+func (m WorkspaceMember) JoinedAt() CreatedAt { return m.joinedAt }
@@ -3,8 +3,13 @@ package domain
 import "errors"
 
 var (
-	ErrValidationFailed = errors.New("validation failed")
-	ErrEntityNotFound   = errors.New("entity not found")
-	ErrIDAlreadySet     = errors.New("ID is already set and cannot be changed")
-	ErrUnknown          = errors.New("unknown error")
+	ErrValidationFailed    = errors.New("validation failed")
+	ErrEntityNotFound      = errors.New("entity not found")
+	ErrEntityAlreadyExists = errors.New("entity already exists")
+	ErrForbidden           = errors.New("operation is not permitted")
+	ErrIDAlreadySet        = errors.New("ID is already set and cannot be changed")
+	ErrTokenInvalid        = errors.New("token is invalid, expired or already used")
+	ErrVersionConflict     = errors.New("entity has been modified since it was read")
+	ErrDocCycle            = errors.New("document cannot be moved under itself or its descendants")
+	ErrUnknown             = errors.New("unknown error")
 )
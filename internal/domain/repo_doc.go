@@ -1,8 +1,39 @@
 package domain
 
+import "time"
+
 type DocRepository interface {
 	Find(id ID) (Doc, error)
-	FindDocs(query DocsQuery) ([]Doc, int, error)
+	FindDocs(query DocsQuery) ([]DocMatch, int, error)
+	// 保存済みのドキュメントの版数がdoc.Version()と異なる場合はErrVersionConflict
 	Save(doc Doc) (Doc, error)
+	// ゴミ箱に移動する。子のドキュメントは削除するドキュメントの親の子として末尾に移す
 	Delete(id ID) error
+	// 版数がversionと異なる場合はErrVersionConflict
+	DeleteWithVersion(id ID, version int) error
+	// ゴミ箱にないすべてのドキュメントをbatchSize件ずつfnに渡す。fnがエラーを返すと中断する
+	EachBatch(batchSize int, fn func(docs []Doc) error) error
+
+	// 保存時に本文から抽出した目次の見出しを出現順に返す
+	FindHeadings(id ID) ([]DocHeading, error)
+
+	// 親からルートまでの祖先をルートから順に返す。自身は含まない
+	FindAncestors(id ID) ([]Doc, error)
+	// parentIDの子のうちviewerIDのユーザーが閲覧できるものを並び順に返す。parentIDがゼロ値の場合はワークスペースの最上位
+	FindChildren(viewerID ID, workspaceID ID, parentID ID) ([]DocTreeNode, error)
+	// ドキュメントをparentIDの子のposition番目に移動し、兄弟の並び順を詰め直す
+	// positionが兄弟の数以上の場合は末尾に移動する。循環する場合はErrDocCycle
+	Move(id ID, parentID ID, position int) (Doc, error)
+
+	// ゴミ箱にないドキュメントの場合はErrEntityNotFound
+	FindTrashed(id ID) (TrashedDoc, error)
+	// ワークスペースのゴミ箱のうち、viewerIDのユーザーが復元できるものを削除日時の新しい順に返す
+	FindTrash(viewerID ID, workspaceID ID, page Page, limit Limit) ([]TrashedDoc, int, error)
+	// 元の親がない場合はワークスペースの最上位の末尾に戻す
+	Restore(id ID) error
+	// ゴミ箱のドキュメントを、リビジョンや共有設定とともに完全に削除する
+	Purge(id ID) error
+	// beforeより前にゴミ箱に移動したドキュメントを完全に削除し、削除した件数を返す
+	// 一度に削除する件数には上限があるため、0件になるまで繰り返し呼び出す
+	PurgeTrashedBefore(before time.Time) (int, error)
 }
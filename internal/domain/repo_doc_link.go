@@ -0,0 +1,9 @@
+package domain
+
+// ドキュメント間のリンクはDocRepository.Saveで本文から抽出して保存する
+type DocLinkRepository interface {
+	// ドキュメントの本文中のリンクを出現順に返す。同じリンク先は1件にまとめる
+	FindOutlinks(sourceID ID) ([]DocLink, error)
+	// targetIDのドキュメントにリンクしている、ゴミ箱にないドキュメントを返す
+	FindBacklinks(targetID ID) ([]Doc, error)
+}
@@ -0,0 +1,12 @@
+package domain
+
+type DocPermissionRepository interface {
+	// 権限が付与されていない場合はErrEntityNotFound
+	Find(docID ID, userID ID) (DocPermission, error)
+	FindByDocID(docID ID) ([]DocPermission, error)
+	// ユーザーに権限が付与されているドキュメントのID
+	FindDocIDsByUserID(userID ID) ([]ID, error)
+	// ドキュメントとユーザーの組ごとに1件のみ保持し、既存の権限は上書きする
+	Save(permission DocPermission) (DocPermission, error)
+	Delete(docID ID, userID ID) error
+}
@@ -0,0 +1,11 @@
+package domain
+
+// リビジョンはDocRepository.Saveで作成されるため、ここでは参照のみ提供する
+type DocRevisionRepository interface {
+	// 指定したドキュメントのリビジョンでない場合はErrEntityNotFound
+	Find(docID ID, id ID) (DocRevision, error)
+	// 新しい順に返す
+	FindByDocID(docID ID) ([]DocRevision, error)
+	// 指定したリビジョンの直前のリビジョンを返す。最初のリビジョンの場合はErrEntityNotFound
+	FindPrevious(revision DocRevision) (DocRevision, error)
+}
@@ -0,0 +1,8 @@
+package domain
+
+type EmailVerificationTokenRepository interface {
+	Save(token EmailVerificationToken) (EmailVerificationToken, error)
+	// 未使用かつ有効期限内のトークンを使用済みにし、対象ユーザーIDを返す
+	Consume(tokenHash string) (ID, error)
+	DeleteByUserID(userID ID) error
+}
@@ -0,0 +1,6 @@
+package domain
+
+type ExternalIdentityRepository interface {
+	FindByIssuerSubject(issuer, subject string) (ExternalIdentity, error)
+	Save(identity ExternalIdentity) (ExternalIdentity, error)
+}
@@ -0,0 +1,10 @@
+package domain
+
+type MFAChallengeRepository interface {
+	Save(challenge MFAChallenge) (MFAChallenge, error)
+	FindByHash(tokenHash string) (MFAChallenge, error)
+	// 失敗回数を1増やし、増やした後の回数を返す
+	RecordFailure(id ID) (int, error)
+	// チャレンジを削除して使用済みにする。既に削除されていればErrTokenInvalid
+	Consume(id ID) error
+}
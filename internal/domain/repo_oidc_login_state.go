@@ -0,0 +1,7 @@
+package domain
+
+type OIDCLoginStateRepository interface {
+	Save(state OIDCLoginState) error
+	// stateを削除して返す。存在しないか期限切れの場合はErrTokenInvalid
+	Consume(stateHash string) (OIDCLoginState, error)
+}
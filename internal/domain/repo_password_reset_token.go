@@ -0,0 +1,8 @@
+package domain
+
+type PasswordResetTokenRepository interface {
+	Save(token PasswordResetToken) (PasswordResetToken, error)
+	// 未使用かつ有効期限内のトークンを使用済みにし、対象ユーザーIDを返す
+	Consume(tokenHash string) (ID, error)
+	DeleteByUserID(userID ID) error
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+type PersonalAccessTokenRepository interface {
+	// 失効済みのトークンは見つからないものとして扱う
+	FindByHash(tokenHash string) (PersonalAccessToken, error)
+	FindByUserID(userID ID) ([]PersonalAccessToken, error)
+	Save(token PersonalAccessToken) (PersonalAccessToken, error)
+	Revoke(id ID, userID ID) error
+	TouchLastUsed(id ID, usedAt time.Time) error
+}
@@ -0,0 +1,10 @@
+package domain
+
+type RecoveryCodeRepository interface {
+	// ユーザーのリカバリーコードをすべて置き換える
+	Replace(userID ID, codes []RecoveryCode) error
+	// 未使用のコードを使用済みにする。該当がなければErrTokenInvalid
+	Consume(userID ID, codeHash string) error
+	CountUnused(userID ID) (int, error)
+	DeleteByUserID(userID ID) error
+}
@@ -0,0 +1,10 @@
+package domain
+
+type RefreshTokenRepository interface {
+	FindByHash(tokenHash string) (RefreshToken, error)
+	Save(token RefreshToken) (RefreshToken, error)
+	// 現在のトークンを失効させ、同じfamilyの次のトークンを保存する
+	Rotate(current RefreshToken, next RefreshToken) (RefreshToken, error)
+	RevokeFamily(familyID ID) error
+	RevokeAllForUser(userID ID) error
+}
@@ -0,0 +1,9 @@
+package domain
+
+// ログアウト済みアクセストークン（jti）のブラックリスト
+// アクセストークンの有効期限まで保持すれば十分なため、期限切れのものは削除してよい
+type RevokedAccessTokenRepository interface {
+	Revoke(jti string, expiresAt ExpiresAt) error
+	IsRevoked(jti string) (bool, error)
+	DeleteExpired() error
+}
@@ -0,0 +1,11 @@
+package domain
+
+// データベースとは別に持つ全文検索インデックス
+// ドキュメントの保存・削除のたびに更新する。データベースが正のため、不整合は再構築で解消する
+// 検索結果には閲覧できないドキュメントも含まれるため、DocsQuery.WithSearchHitsでDocRepository.FindDocsに渡して絞り込む
+type SearchIndex interface {
+	Index(doc Doc) error
+	Delete(id ID) error
+	// scopeの中で一致したドキュメントを関連度の高い順に最大limit件返す
+	Search(text SearchText, scope SearchScope, limit int) ([]SearchHit, error)
+}
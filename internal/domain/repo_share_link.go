@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+type ShareLinkRepository interface {
+	// 失効済みのリンクは返さない
+	FindByHash(tokenHash string) (ShareLink, error)
+	FindByDocID(docID ID) ([]ShareLink, error)
+	Save(link ShareLink) (ShareLink, error)
+	Revoke(id ID, docID ID) error
+	// 閲覧回数を1増やし、最終閲覧日時を更新する
+	RecordAccess(id ID) error
+	// パスワードの間違いを記録し、失敗回数を返す。最後の失敗からwindow以上経っている場合は1から数え直す
+	RecordFailure(id ID, window time.Duration) (int, error)
+	ResetFailures(id ID) error
+}
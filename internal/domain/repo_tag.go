@@ -0,0 +1,13 @@
+package domain
+
+// タグはワークスペースごとに、ゴミ箱にないドキュメントに付いているものを扱う
+type TagRepository interface {
+	// prefixで始まるタグを、ドキュメント数の多い順に最大limit件返す。prefixは大文字・小文字を区別しない
+	FindCounts(workspaceID ID, prefix string, limit int) ([]TagCount, error)
+	// sourcesのタグをtargetに置き換え、変更したドキュメントを返す
+	// 変更はeditorIDのユーザーによる保存として、版数を上げてリビジョンを残す
+	// sourcesのいずれも使われていない場合はErrEntityNotFound
+	Merge(workspaceID ID, sources []string, target string, editorID ID) ([]Doc, error)
+	// タグをドキュメントから外し、変更したドキュメントを返す。使われていない場合はErrEntityNotFound
+	Delete(workspaceID ID, name string, editorID ID) ([]Doc, error)
+}
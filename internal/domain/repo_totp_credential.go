@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+type TOTPCredentialRepository interface {
+	Find(userID ID) (TOTPCredential, error)
+	// ユーザーごとに1件のみ保持し、既存の設定は上書きする
+	Save(credential TOTPCredential) (TOTPCredential, error)
+	// stepが最後に使用したステップより新しい場合のみ記録する。そうでなければErrTokenInvalid
+	MarkStepUsed(userID ID, step int64) error
+	// 2要素目の検証の失敗を記録し、失敗回数を返す。最後の失敗からwindow以上経っている場合は1から数え直す
+	RecordFailure(userID ID, window time.Duration) (int, error)
+	ResetFailures(userID ID) error
+	Delete(userID ID) error
+}
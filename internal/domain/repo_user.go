@@ -2,6 +2,7 @@ package domain
 
 type UserRepository interface {
 	Find(id ID) (User, error)
+	FindByEmail(email Email) (User, error)
 	Save(user User) (User, error)
 	Delete(id ID) error
 }
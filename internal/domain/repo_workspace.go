@@ -0,0 +1,10 @@
+package domain
+
+type WorkspaceRepository interface {
+	Find(id ID) (Workspace, error)
+	// ユーザーの個人ワークスペースを返す。未作成の場合はErrEntityNotFound
+	FindPersonal(userID ID) (Workspace, error)
+	// ユーザーが所属するワークスペースを返す
+	FindByUserID(userID ID) ([]Workspace, error)
+	Save(workspace Workspace) (Workspace, error)
+}
@@ -0,0 +1,11 @@
+package domain
+
+type WorkspaceMemberRepository interface {
+	// メンバーでない場合はErrEntityNotFound
+	Find(workspaceID ID, userID ID) (WorkspaceMember, error)
+	FindByWorkspaceID(workspaceID ID) ([]WorkspaceMember, error)
+	CountOwners(workspaceID ID) (int, error)
+	// ワークスペースとユーザーの組ごとに1件のみ保持し、既存の権限は上書きする
+	Save(member WorkspaceMember) (WorkspaceMember, error)
+	Delete(workspaceID ID, userID ID) error
+}
@@ -0,0 +1,75 @@
+package domain
+
+import "time"
+
+// 検索条件の対象
+type DocConditionField int
+
+const (
+	DocConditionTag DocConditionField = iota
+	DocConditionAuthor
+	DocConditionCreated
+	DocConditionUpdated
+	// タイトル・本文の全文検索。valueは語またはフレーズ
+	DocConditionText
+)
+
+// ドキュメントの検索条件の1つ
+type DocCondition struct {
+	field    DocConditionField
+	negated  bool
+	value    string
+	authorID ID
+	// 日時の条件はfrom以上to未満。nilの場合は制限しない
+	from *time.Time
+	to   *time.Time
+}
+
+func NewTagCondition(tag string, negated bool) DocCondition {
+	return DocCondition{field: DocConditionTag, negated: negated, value: tag}
+}
+
+func NewAuthorCondition(authorID ID, negated bool) DocCondition {
+	return DocCondition{field: DocConditionAuthor, negated: negated, authorID: authorID}
+}
+
+func NewCreatedCondition(from, to *time.Time, negated bool) DocCondition {
+	return DocCondition{field: DocConditionCreated, negated: negated, from: from, to: to}
+}
+
+func NewUpdatedCondition(from, to *time.Time, negated bool) DocCondition {
+	return DocCondition{field: DocConditionUpdated, negated: negated, from: from, to: to}
+}
+
+func NewTextCondition(text string, negated bool) DocCondition {
+	return DocCondition{field: DocConditionText, negated: negated, value: text}
+}
+
+func (c DocCondition) Field() DocConditionField { return c.field }
+
+// trueの場合は条件に一致しないドキュメントを対象にする
+func (c DocCondition) Negated() bool    { return c.negated }
+func (c DocCondition) Value() string    { return c.value }
+func (c DocCondition) AuthorID() ID     { return c.authorID }
+func (c DocCondition) From() *time.Time { return c.from }
+func (c DocCondition) To() *time.Time   { return c.to }
+
+// 検索条件の組み合わせ
+// すべてのグループを満たすドキュメントが対象で（AND）、グループ内の条件はいずれかを満たせばよい（OR）
+type DocFilter struct {
+	groups [][]DocCondition
+}
+
+func NewDocFilter(groups [][]DocCondition) DocFilter {
+	return DocFilter{groups: groups}
+}
+
+// Andはgroupのいずれかを満たすことを条件に加えたフィルターを返す
+func (f DocFilter) And(group ...DocCondition) DocFilter {
+	groups := make([][]DocCondition, len(f.groups), len(f.groups)+1)
+	copy(groups, f.groups)
+	return DocFilter{groups: append(groups, group)}
+}
+
+func (f DocFilter) Groups() [][]DocCondition { return f.groups }
+func (f DocFilter) IsEmpty() bool            { return len(f.groups) == 0 }
@@ -0,0 +1,23 @@
+package domain
+
+// 目次の見出し
+type DocHeading struct {
+	level  int
+	text   string
+	anchor string
+}
+
+func NewDocHeading(level int, text string, anchor string) DocHeading {
+	return DocHeading{
+		level:  level,
+		text:   text,
+		anchor: anchor,
+	}
+}
+
+// 1から6
+func (h DocHeading) Level() int   { return h.level }
+func (h DocHeading) Text() string { return h.text }
+
+// ドキュメント内で一意なアンカー。HTMLに変換したときの見出しのidと一致する
+func (h DocHeading) Anchor() string { return h.anchor }
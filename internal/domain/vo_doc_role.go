@@ -0,0 +1,57 @@
+package domain
+
+import "fmt"
+
+// ドキュメントに対する共有相手の権限
+type DocRole struct {
+	value string
+}
+
+const (
+	DocRoleViewer = "viewer"
+	DocRoleEditor = "editor"
+	DocRoleOwner  = "owner"
+)
+
+func NewDocRole(value string) (DocRole, error) {
+	switch value {
+	case DocRoleViewer, DocRoleEditor, DocRoleOwner:
+		return DocRole{value: value}, nil
+	default:
+		return DocRole{}, fmt.Errorf("invalid document role: %s (must be 'viewer', 'editor' or 'owner')", value)
+	}
+}
+
+// 作成者は常にownerとして扱う
+func OwnerDocRole() DocRole {
+	return DocRole{value: DocRoleOwner}
+}
+
+func (r DocRole) Value() string  { return r.value }
+func (r DocRole) String() string { return r.value }
+func (r DocRole) CanRead() bool  { return r.value != "" }
+func (r DocRole) CanEdit() bool  { return r.value == DocRoleEditor || r.value == DocRoleOwner }
+
+// 削除や共有設定の変更ができるか
+func (r DocRole) CanManage() bool { return r.value == DocRoleOwner }
+
+// 権限の強い方を返す
+func (r DocRole) Max(other DocRole) DocRole {
+	if other.rank() > r.rank() {
+		return other
+	}
+	return r
+}
+
+func (r DocRole) rank() int {
+	switch r.value {
+	case DocRoleViewer:
+		return 1
+	case DocRoleEditor:
+		return 2
+	case DocRoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -1,32 +1,45 @@
 package domain
 
 type DocsQuery struct {
+	viewerID     ID
+	workspaceID  ID
 	page         Page
 	limit        Limit
 	sortBy       SortBy
 	sortOrder    SortOrder
-	tags         Tags
 	createdRange DateRange
 	updatedRange DateRange
+	sharedOnly   bool
+	text         SearchText
+	filter       DocFilter
+	searchHits   []SearchHit
 }
 
 func NewDocsQuery(
+	viewerID ID,
+	workspaceID ID,
 	page Page,
 	limit Limit,
 	sortBy SortBy,
 	sortOrder SortOrder,
-	tags Tags,
 	createdRange DateRange,
 	updatedRange DateRange,
+	sharedOnly bool,
+	text SearchText,
+	filter DocFilter,
 ) DocsQuery {
 	return DocsQuery{
+		viewerID:     viewerID,
+		workspaceID:  workspaceID,
 		page:         page,
 		limit:        limit,
 		sortBy:       sortBy,
 		sortOrder:    sortOrder,
-		tags:         tags,
 		createdRange: createdRange,
 		updatedRange: updatedRange,
+		sharedOnly:   sharedOnly,
+		text:         text,
+		filter:       filter,
 	}
 }
 
@@ -36,20 +49,44 @@ func DefaultDocsQuery() DocsQuery {
 		limit:        DefaultLimit(),
 		sortBy:       DefaultSortBy(),
 		sortOrder:    DefaultSortOrder(),
-		tags:         Tags{},
 		createdRange: DateRange{},
 		updatedRange: DateRange{},
 	}
 }
 
-func (q DocsQuery) Page() Page               { return q.page }
-func (q DocsQuery) Limit() Limit             { return q.limit }
-func (q DocsQuery) SortBy() SortBy           { return q.sortBy }
-func (q DocsQuery) SortOrder() SortOrder     { return q.sortOrder }
-func (q DocsQuery) Tags() Tags               { return q.tags }
-func (q DocsQuery) CreatedRange() DateRange  { return q.createdRange }
-func (q DocsQuery) UpdatedRange() DateRange  { return q.updatedRange }
+// 検索するユーザー。このユーザーが閲覧できるドキュメントのみを対象にする
+func (q DocsQuery) ViewerID() ID            { return q.viewerID }
+func (q DocsQuery) WorkspaceID() ID         { return q.workspaceID }
+func (q DocsQuery) Page() Page              { return q.page }
+func (q DocsQuery) Limit() Limit            { return q.limit }
+func (q DocsQuery) SortBy() SortBy          { return q.sortBy }
+func (q DocsQuery) SortOrder() SortOrder    { return q.sortOrder }
+func (q DocsQuery) CreatedRange() DateRange { return q.createdRange }
+func (q DocsQuery) UpdatedRange() DateRange { return q.updatedRange }
+
+// trueの場合は他のユーザーから共有されたドキュメントのみを対象にする
+// 共有されたドキュメントは別のワークスペースのものもあるため、ワークスペースでは絞り込まない
+func (q DocsQuery) SharedOnly() bool { return q.sharedOnly }
+
+// 空でない場合はタイトル・本文の全文検索で絞り込む
+func (q DocsQuery) Text() SearchText { return q.text }
+
+// タグ・作成者・日付などの条件
+func (q DocsQuery) Filter() DocFilter { return q.filter }
+
+// WithSearchHitsは全文検索を検索インデックスの結果で行うクエリを返す
+// 指定しない場合は、データベースの全文検索インデックスを使う
+func (q DocsQuery) WithSearchHits(hits []SearchHit) DocsQuery {
+	if hits == nil {
+		hits = []SearchHit{}
+	}
+	q.searchHits = hits
+	return q
+}
+
+func (q DocsQuery) SearchHits() []SearchHit { return q.searchHits }
+func (q DocsQuery) UsesSearchIndex() bool   { return q.searchHits != nil }
 
 func (q DocsQuery) Offset() int {
 	return (q.page.Value() - 1) * q.limit.Value()
-}
\ No newline at end of file
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// 有効期限。ゼロ値は無期限を表す
+type ExpiresAt struct {
+	value time.Time
+}
+
+func NewExpiresAt(value time.Time) ExpiresAt {
+	return ExpiresAt{value: value}
+}
+
+func NewExpiresAtAfter(d time.Duration) ExpiresAt {
+	return ExpiresAt{value: time.Now().Add(d)}
+}
+
+func (e ExpiresAt) Value() time.Time { return e.value }
+func (e ExpiresAt) String() string   { return e.value.Format(time.RFC3339) }
+func (e ExpiresAt) IsZero() bool     { return e.value.IsZero() }
+func (e ExpiresAt) IsExpired() bool  { return !e.value.IsZero() && !time.Now().Before(e.value) }
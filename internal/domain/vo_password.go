@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 128
+)
+
+// 平文パスワード。ハッシュ化前の入力値の検証にのみ使用し、永続化しない
+type Password struct {
+	value string
+}
+
+func NewPassword(value string) (Password, error) {
+	length := utf8.RuneCountInString(value)
+	if length < minPasswordLength {
+		return Password{}, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if length > maxPasswordLength {
+		return Password{}, fmt.Errorf("password cannot exceed %d characters", maxPasswordLength)
+	}
+	return Password{value: value}, nil
+}
+
+func (p Password) Value() string  { return p.value }
+func (p Password) String() string { return "********" }
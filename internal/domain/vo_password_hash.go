@@ -0,0 +1,15 @@
+package domain
+
+// エンコード済みのパスワードハッシュ（PHC文字列形式）
+// 外部IdPのみでログインするユーザーなど、パスワード未設定の場合は空
+type PasswordHash struct {
+	value string
+}
+
+func NewPasswordHash(value string) PasswordHash {
+	return PasswordHash{value: value}
+}
+
+func (p PasswordHash) Value() string  { return p.value }
+func (p PasswordHash) String() string { return p.value }
+func (p PasswordHash) IsEmpty() bool  { return p.value == "" }
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	ScopeDocsRead  = "docs:read"
+	ScopeDocsWrite = "docs:write"
+	ScopeUserRead  = "user:read"
+	ScopeUserWrite = "user:write"
+)
+
+var allScopes = []string{ScopeDocsRead, ScopeDocsWrite, ScopeUserRead, ScopeUserWrite}
+
+// パーソナルアクセストークンに付与する権限の集合
+type Scopes struct {
+	values []string
+}
+
+func NewScopes(values []string) (Scopes, error) {
+	if len(values) == 0 {
+		return Scopes{}, fmt.Errorf("at least one scope is required")
+	}
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if !isKnownScope(v) {
+			return Scopes{}, fmt.Errorf("invalid scope: %s. allowed values: %s", v, strings.Join(allScopes, ", "))
+		}
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		normalized = append(normalized, v)
+	}
+	sort.Strings(normalized)
+	return Scopes{values: normalized}, nil
+}
+
+// ログインセッション（JWT）には全ての権限を与える
+func AllScopes() Scopes {
+	values := append([]string{}, allScopes...)
+	sort.Strings(values)
+	return Scopes{values: values}
+}
+
+func isKnownScope(v string) bool {
+	for _, s := range allScopes {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Scopes) Values() []string { return append([]string{}, s.values...) }
+func (s Scopes) String() string   { return strings.Join(s.values, " ") }
+
+func (s Scopes) Has(scope string) bool {
+	for _, v := range s.values {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package domain
+
+// 検索インデックスで一致したドキュメントと関連度
+type SearchHit struct {
+	docID ID
+	score float64
+}
+
+func NewSearchHit(docID ID, score float64) SearchHit {
+	return SearchHit{docID: docID, score: score}
+}
+
+func (h SearchHit) DocID() ID      { return h.docID }
+func (h SearchHit) Score() float64 { return h.score }
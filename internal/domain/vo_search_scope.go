@@ -0,0 +1,27 @@
+package domain
+
+// 検索インデックスで検索する範囲。ワークスペース、またはドキュメントで絞り込む
+// 上限件数で打ち切られる前に閲覧できる範囲へ絞り込むため、FindDocsと同じ範囲を指定する
+type SearchScope struct {
+	workspaceID ID
+	docIDs      []ID
+}
+
+// ワークスペースのドキュメントを検索する
+func NewWorkspaceSearchScope(workspaceID ID) SearchScope {
+	return SearchScope{workspaceID: workspaceID}
+}
+
+// 指定したドキュメントのみを検索する。空の場合は何にも一致しない
+func NewDocsSearchScope(docIDs []ID) SearchScope {
+	if docIDs == nil {
+		docIDs = []ID{}
+	}
+	return SearchScope{docIDs: docIDs}
+}
+
+func (s SearchScope) WorkspaceID() ID { return s.workspaceID }
+func (s SearchScope) DocIDs() []ID    { return s.docIDs }
+
+// NewDocsSearchScopeで作成した場合はtrue
+func (s SearchScope) IsDocs() bool { return s.docIDs != nil }
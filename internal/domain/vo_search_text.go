@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ドキュメントのタイトル・本文を対象にした全文検索の文字列
+// 空白で区切った語をすべて含むドキュメントに一致する。"..."で囲んだフレーズは語順どおりに含むものに一致する
+type SearchText struct {
+	value string
+}
+
+func NewSearchText(value string) (SearchText, error) {
+	value = strings.TrimSpace(value)
+	if utf8.RuneCountInString(value) > 200 {
+		return SearchText{}, fmt.Errorf("search text cannot exceed 200 characters")
+	}
+	return SearchText{value: value}, nil
+}
+
+func (s SearchText) Value() string  { return s.value }
+func (s SearchText) String() string { return s.value }
+func (s SearchText) IsEmpty() bool  { return s.value == "" }
+
+// 空白で区切った検索語。"..."で囲んだ部分は、空白を含めて1つの語として扱う
+func (s SearchText) Terms() []string {
+	var terms []string
+	for i, part := range strings.Split(s.value, `"`) {
+		// 引用符の内側は奇数番目。閉じていない引用符は行末までをフレーズとして扱う
+		if i%2 == 1 {
+			if phrase := strings.Join(strings.Fields(part), " "); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(part)...)
+	}
+	return terms
+}
@@ -10,14 +10,16 @@ const (
 	SortByCreatedAt = "created_at"
 	SortByUpdatedAt = "updated_at"
 	SortByTitle     = "title"
+	// 全文検索の関連度順。検索文字列を指定しない場合は作成日時順として扱う
+	SortByRelevance = "relevance"
 )
 
 func NewSortBy(value string) (SortBy, error) {
 	switch value {
-	case SortByCreatedAt, SortByUpdatedAt, SortByTitle:
+	case SortByCreatedAt, SortByUpdatedAt, SortByTitle, SortByRelevance:
 		return SortBy{value: value}, nil
 	default:
-		return SortBy{}, fmt.Errorf("invalid sort_by field: %s. allowed values: created_at, updated_at, title", value)
+		return SortBy{}, fmt.Errorf("invalid sort_by field: %s. allowed values: created_at, updated_at, title, relevance", value)
 	}
 }
 
@@ -25,5 +27,6 @@ func DefaultSortBy() SortBy {
 	return SortBy{value: SortByCreatedAt}
 }
 
-func (s SortBy) Value() string  { return s.value }
-func (s SortBy) String() string { return s.value }
\ No newline at end of file
+func (s SortBy) Value() string     { return s.value }
+func (s SortBy) String() string    { return s.value }
+func (s SortBy) IsRelevance() bool { return s.value == SortByRelevance }
@@ -0,0 +1,14 @@
+package domain
+
+// タグと、タグを付けたドキュメントの数
+type TagCount struct {
+	name  string
+	count int
+}
+
+func NewTagCount(name string, count int) TagCount {
+	return TagCount{name: name, count: count}
+}
+
+func (t TagCount) Name() string { return t.name }
+func (t TagCount) Count() int   { return t.count }
@@ -7,45 +7,65 @@ import (
 )
 
 type Tags struct {
-	value string // カンマ区切り文字列で保持
+	values []string // 指定された順序で保持する
 }
 
 // カンマ区切り文字列からTagsを生成
 func NewTags(csv string) (Tags, error) {
 	csv = strings.TrimSpace(csv)
 	if csv == "" {
-		return Tags{value: ""}, nil
+		return Tags{}, nil
 	}
 
 	parts := strings.Split(csv, ",")
-	seen := make(map[string]bool)
 	for i, part := range parts {
-		tag := strings.TrimSpace(part)
+		if strings.TrimSpace(part) == "" {
+			return Tags{}, fmt.Errorf("tag at position %d is empty", i+1)
+		}
+	}
+	return NewTagList(parts)
+}
+
+// タグ名の一覧からTagsを生成
+// タグ名は前後の空白を除き、大文字・小文字を区別する
+func NewTagList(values []string) (Tags, error) {
+	tags := make([]string, 0, len(values))
+	seen := make(map[string]bool)
+	for i, value := range values {
+		tag := strings.TrimSpace(value)
 		if tag == "" {
 			return Tags{}, fmt.Errorf("tag at position %d is empty", i+1)
 		}
-		if utf8.RuneCountInString(tag) < 1 || utf8.RuneCountInString(tag) > 50 {
-			return Tags{}, fmt.Errorf("tag '%s' must be 1-50 characters", tag)
+		if err := ValidateTagName(tag); err != nil {
+			return Tags{}, err
 		}
 		if seen[tag] {
 			return Tags{}, fmt.Errorf("duplicate tag: %s", tag)
 		}
 		seen[tag] = true
+		tags = append(tags, tag)
 	}
-
-	return Tags{value: csv}, nil
+	return Tags{values: tags}, nil
 }
 
-func (ts Tags) Values() []string {
-	if ts.value == "" {
-		return []string{}
+// ValidateTagNameはタグ名として使えるか検証する
+// リビジョンや検索条件ではカンマ区切りで扱うため、カンマは使えない
+func ValidateTagName(tag string) error {
+	if utf8.RuneCountInString(tag) < 1 || utf8.RuneCountInString(tag) > 50 {
+		return fmt.Errorf("tag '%s' must be 1-50 characters", tag)
 	}
-	parts := strings.Split(ts.value, ",")
-	for i := range parts {
-		parts[i] = strings.TrimSpace(parts[i])
+	if strings.Contains(tag, ",") {
+		return fmt.Errorf("tag '%s' cannot contain a comma", tag)
 	}
-	return parts
+	return nil
+}
+
+func (ts Tags) Values() []string {
+	values := make([]string, len(ts.values))
+	copy(values, ts.values)
+	return values
 }
 
-func (ts Tags) String() string { return ts.value }
-func (ts Tags) IsEmpty() bool  { return ts.value == "" }
+// カンマ区切り文字列
+func (ts Tags) String() string { return strings.Join(ts.values, ",") }
+func (ts Tags) IsEmpty() bool  { return len(ts.values) == 0 }
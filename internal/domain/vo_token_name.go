@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type TokenName struct {
+	value string
+}
+
+func NewTokenName(value string) (TokenName, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return TokenName{}, fmt.Errorf("token name cannot be empty")
+	}
+	if utf8.RuneCountInString(value) > 100 {
+		return TokenName{}, fmt.Errorf("token name cannot exceed 100 characters")
+	}
+	return TokenName{value: value}, nil
+}
+
+func (t TokenName) Value() string  { return t.value }
+func (t TokenName) String() string { return t.value }
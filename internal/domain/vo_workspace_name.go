@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type WorkspaceName struct {
+	value string
+}
+
+func NewWorkspaceName(value string) (WorkspaceName, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return WorkspaceName{}, fmt.Errorf("workspace name cannot be empty")
+	}
+	if utf8.RuneCountInString(value) > 100 {
+		return WorkspaceName{}, fmt.Errorf("workspace name cannot exceed 100 characters")
+	}
+	return WorkspaceName{value: value}, nil
+}
+
+func (w WorkspaceName) Value() string  { return w.value }
+func (w WorkspaceName) String() string { return w.value }
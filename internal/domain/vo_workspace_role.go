@@ -0,0 +1,54 @@
+package domain
+
+import "fmt"
+
+// ワークスペースでのメンバーの権限
+type WorkspaceRole struct {
+	value string
+}
+
+const (
+	WorkspaceRoleMember = "member"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleOwner  = "owner"
+)
+
+func NewWorkspaceRole(value string) (WorkspaceRole, error) {
+	switch value {
+	case WorkspaceRoleMember, WorkspaceRoleAdmin, WorkspaceRoleOwner:
+		return WorkspaceRole{value: value}, nil
+	default:
+		return WorkspaceRole{}, fmt.Errorf("invalid workspace role: %s (must be 'member', 'admin' or 'owner')", value)
+	}
+}
+
+func OwnerWorkspaceRole() WorkspaceRole {
+	return WorkspaceRole{value: WorkspaceRoleOwner}
+}
+
+func (r WorkspaceRole) Value() string  { return r.value }
+func (r WorkspaceRole) String() string { return r.value }
+func (r WorkspaceRole) IsOwner() bool  { return r.value == WorkspaceRoleOwner }
+
+// メンバーの招待・削除ができるか
+func (r WorkspaceRole) CanManageMembers() bool {
+	return r.value == WorkspaceRoleAdmin || r.value == WorkspaceRoleOwner
+}
+
+// タグの名前変更・統合・削除など、ワークスペース全体のドキュメントを一括で変更できるか
+func (r WorkspaceRole) CanManageTags() bool {
+	return r.value == WorkspaceRoleAdmin || r.value == WorkspaceRoleOwner
+}
+
+// ワークスペース内のドキュメントに対する権限
+// メンバーは全ドキュメントを編集でき、admin以上は削除や共有設定の変更もできる
+func (r WorkspaceRole) DocRole() DocRole {
+	switch r.value {
+	case WorkspaceRoleAdmin, WorkspaceRoleOwner:
+		return DocRole{value: DocRoleOwner}
+	case WorkspaceRoleMember:
+		return DocRole{value: DocRoleEditor}
+	default:
+		return DocRole{}
+	}
+}
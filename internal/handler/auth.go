@@ -2,15 +2,25 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/password"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"github.com/iotassss/gizzmd/internal/token"
 	"gorm.io/gorm"
 )
 
-var jwtSecret = []byte("your-secret-key") // 本番では環境変数等で管理
+const (
+	accessTokenTTL  = 1 * time.Hour
+	refreshTokenTTL = 14 * 24 * time.Hour
+)
 
 type LoginRequest struct {
 	Username string `json:"username"`
@@ -18,10 +28,20 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int    `json:"expires_in"`
-	User        User   `json:"user"`
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+	User             User   `json:"user"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -29,84 +49,346 @@ type ErrorResponse struct {
 }
 
 type User struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	ID                string `json:"id"`
+	Email             string `json:"email"`
+	Name              string `json:"name"`
+	ActiveWorkspaceID string `json:"active_workspace_id,omitempty"`
+}
+
+// 検証済みのアクセストークン
+type AccessToken struct {
+	User      User
+	JTI       string
+	ExpiresAt time.Time
 }
 
-// ダミー認証: 本来はDB参照
-func authenticateUser(email, password string) (*User, bool) {
-	if email == "user@example.com" && password == "password123" {
-		return &User{
-			ID:    "123e4567-e89b-12d3-a456-426614174000",
-			Email: email,
-			Name:  "John Doe",
-		}, true
+// メールアドレスとパスワードでユーザーを認証する
+// コストパラメータが変更されていた場合は、ログイン成功時にハッシュを再生成して保存する
+func authenticateUser(userRepo *gormrepo.UserRepository, hasher *password.Hasher, email, rawPassword string) (domain.User, bool, error) {
+	emailVO, err := domain.NewEmail(email)
+	if err != nil {
+		hasher.VerifyDummy(rawPassword)
+		return domain.User{}, false, nil
+	}
+
+	user, err := userRepo.FindByEmail(emailVO)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			hasher.VerifyDummy(rawPassword)
+			return domain.User{}, false, nil
+		}
+		return domain.User{}, false, err
+	}
+	if user.PasswordHash().IsEmpty() {
+		hasher.VerifyDummy(rawPassword)
+		return domain.User{}, false, nil
+	}
+
+	ok, needsRehash, err := hasher.Verify(rawPassword, user.PasswordHash().Value())
+	if err != nil {
+		return domain.User{}, false, err
+	}
+	if !ok {
+		return domain.User{}, false, nil
+	}
+
+	if needsRehash {
+		hash, err := hasher.Hash(rawPassword)
+		if err != nil {
+			return domain.User{}, false, err
+		}
+		if user, err = userRepo.Save(withPasswordHash(user, hash)); err != nil {
+			return domain.User{}, false, err
+		}
+	}
+
+	return user, true, nil
+}
+
+func toAuthUser(user domain.User) *User {
+	authUser := &User{
+		ID:    user.ID().String(),
+		Email: user.Email().String(),
+		Name:  user.AuthorName().String(),
+	}
+	if !user.ActiveWorkspaceID().IsNil() {
+		authUser.ActiveWorkspaceID = user.ActiveWorkspaceID().String()
 	}
-	return nil, false
+	return authUser
 }
 
-func generateJWT(user *User) (string, int, error) {
-	expiresIn := 3600
-	expiresAt := time.Now().Add(time.Duration(expiresIn) * time.Second)
+func generateJWT(tokens *token.Manager, user *User) (string, int, error) {
 	claims := jwt.MapClaims{
+		"jti":   domain.GenerateID().String(),
 		"sub":   user.ID,
 		"email": user.Email,
 		"name":  user.Name,
-		"exp":   expiresAt.Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString(jwtSecret)
-	return signed, expiresIn, err
+	if user.ActiveWorkspaceID != "" {
+		claims["wid"] = user.ActiveWorkspaceID
+	}
+	signed, _, err := tokens.Sign(claims, accessTokenTTL)
+	return signed, int(accessTokenTTL.Seconds()), err
+}
+
+// アクセストークンとリフレッシュトークンを発行する
+// リフレッシュトークンはログインごとに新しいfamilyとして発行する
+func issueLoginTokens(tokens *token.Manager, refreshRepo *gormrepo.RefreshTokenRepository, user domain.User) (LoginResponse, error) {
+	authUser := toAuthUser(user)
+	accessToken, expiresIn, err := generateJWT(tokens, authUser)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	plain, hash, err := securetoken.Generate()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	refreshToken := domain.NewRefreshToken(
+		domain.GenerateID(),
+		user.ID(),
+		domain.GenerateID(),
+		hash,
+		domain.NewExpiresAtAfter(refreshTokenTTL),
+		false,
+	)
+	if _, err := refreshRepo.Save(refreshToken); err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        expiresIn,
+		RefreshToken:     plain,
+		RefreshExpiresIn: int(refreshTokenTTL.Seconds()),
+		User:             *authUser,
+	}, nil
 }
 
 // ログイン
-func NewLoginHandler(db *gorm.DB) gin.HandlerFunc {
+// 2段階認証が有効なユーザーには、トークンの代わりにMFAChallengeResponseを返す
+func NewLoginHandler(db *gorm.DB, hasher *password.Hasher, tokens *token.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		refreshRepo := gormrepo.NewRefreshTokenRepository(db, c.Request.Context())
+		totpRepo := gormrepo.NewTOTPCredentialRepository(db, c.Request.Context())
+		challengeRepo := gormrepo.NewMFAChallengeRepository(db, c.Request.Context())
+
 		var req LoginRequest
 		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
 			return
 		}
-		user, ok := authenticateUser(req.Username, req.Password)
+		authenticated, ok, err := authenticateUser(userRepo, hasher, req.Username, req.Password)
+		if err != nil {
+			slog.Error("failed to authenticate user", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to authenticate user"})
+			return
+		}
 		if !ok {
 			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "The provided username or password is incorrect"})
 			return
 		}
-		token, expiresIn, err := generateJWT(user)
+		if !authenticated.EmailVerified() {
+			c.JSON(http.StatusForbidden, ErrorResponse{Message: "Email address has not been verified"})
+			return
+		}
+
+		// 2段階認証が有効な場合は、トークンの代わりにチャレンジを返して /login/mfa で続きを受け付ける
+		if _, err := findEnabledTOTP(totpRepo, authenticated.ID()); err == nil {
+			challenge, err := issueMFAChallenge(challengeRepo, authenticated.ID())
+			if err != nil {
+				slog.Error("failed to issue mfa challenge", slog.Any("error", err))
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to authenticate user"})
+				return
+			}
+			c.JSON(http.StatusOK, challenge)
+			return
+		} else if !errors.Is(err, domain.ErrEntityNotFound) {
+			slog.Error("failed to find totp credential", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to authenticate user"})
+			return
+		}
+
+		resp, err := issueLoginTokens(tokens, refreshRepo, authenticated)
 		if err != nil {
+			slog.Error("failed to issue tokens", slog.Any("error", err))
 			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to generate token"})
 			return
 		}
-		resp := LoginResponse{
-			AccessToken: token,
-			TokenType:   "Bearer",
-			ExpiresIn:   expiresIn,
-			User:        *user,
-		}
 		c.JSON(http.StatusOK, resp)
 	}
 }
 
+// トークン更新
+// リフレッシュトークンは1回限り有効で、使用するたびに新しいトークンへローテーションする
+func NewRefreshTokenHandler(db *gorm.DB, tokens *token.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		refreshRepo := gormrepo.NewRefreshTokenRepository(db, c.Request.Context())
+
+		var req RefreshTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+
+		current, err := refreshRepo.FindByHash(securetoken.Hash(req.RefreshToken))
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid or expired refresh token"})
+				return
+			}
+			slog.Error("failed to find refresh token", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to refresh token"})
+			return
+		}
+		revokeFamily := func() {
+			slog.Warn("refresh token reuse detected", slog.String("user_id", current.UserID().String()), slog.String("family_id", current.FamilyID().String()))
+			if err := refreshRepo.RevokeFamily(current.FamilyID()); err != nil {
+				slog.Error("failed to revoke refresh token family", slog.Any("error", err))
+			}
+		}
+		if current.Revoked() {
+			revokeFamily()
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid or expired refresh token"})
+			return
+		}
+		if current.ExpiresAt().IsExpired() {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid or expired refresh token"})
+			return
+		}
+
+		user, err := userRepo.Find(current.UserID())
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid or expired refresh token"})
+			return
+		}
+
+		plain, hash, err := securetoken.Generate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to refresh token"})
+			return
+		}
+		next := domain.NewRefreshToken(
+			domain.GenerateID(),
+			user.ID(),
+			current.FamilyID(),
+			hash,
+			domain.NewExpiresAtAfter(refreshTokenTTL),
+			false,
+		)
+		if _, err := refreshRepo.Rotate(current, next); err != nil {
+			if errors.Is(err, domain.ErrTokenInvalid) {
+				revokeFamily()
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid or expired refresh token"})
+				return
+			}
+			slog.Error("failed to rotate refresh token", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to refresh token"})
+			return
+		}
+
+		authUser := toAuthUser(user)
+		accessToken, expiresIn, err := generateJWT(tokens, authUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, LoginResponse{
+			AccessToken:      accessToken,
+			TokenType:        "Bearer",
+			ExpiresIn:        expiresIn,
+			RefreshToken:     plain,
+			RefreshExpiresIn: int(refreshTokenTTL.Seconds()),
+			User:             *authUser,
+		})
+	}
+}
+
+// ログアウト
+// アクセストークンのjtiを有効期限までブラックリストに登録し、リフレッシュトークンのfamilyを失効させる
+func NewLogoutHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refreshRepo := gormrepo.NewRefreshTokenRepository(db, c.Request.Context())
+		revokedRepo := gormrepo.NewRevokedAccessTokenRepository(db, c.Request.Context())
+
+		userID := c.GetString("user_id")
+		jti := c.GetString("token_jti")
+		expiresAt := c.GetTime("token_expires_at")
+
+		var req LogoutRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+				return
+			}
+		}
+
+		if req.RefreshToken != "" {
+			token, err := refreshRepo.FindByHash(securetoken.Hash(req.RefreshToken))
+			if err != nil && !errors.Is(err, domain.ErrEntityNotFound) {
+				slog.Error("failed to find refresh token", slog.Any("error", err))
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to logout"})
+				return
+			}
+			// 他人のリフレッシュトークンは失効させない
+			if err == nil && token.UserID().String() == userID {
+				if err := refreshRepo.RevokeFamily(token.FamilyID()); err != nil {
+					slog.Error("failed to revoke refresh token family", slog.Any("error", err))
+					c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to logout"})
+					return
+				}
+			}
+		}
+
+		if err := revokedRepo.Revoke(jti, domain.NewExpiresAt(expiresAt)); err != nil {
+			slog.Error("failed to revoke access token", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to logout"})
+			return
+		}
+		if err := revokedRepo.DeleteExpired(); err != nil {
+			slog.Error("failed to delete expired revoked access tokens", slog.Any("error", err))
+		}
+
+		c.JSON(http.StatusOK, MessageResponse{Message: "Logout successful"})
+	}
+}
+
+// 公開鍵の一覧（JWKS）
+// 他のサービスがgizzmdの発行したトークンを検証するために使用する
+func NewJWKSHandler(tokens *token.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, tokens.JWKS())
+	}
+}
+
 // JWT検証用ミドルウェア例
-func ValidateJWT(tokenString string) (*User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return jwtSecret, nil
-	})
-	if err != nil || !token.Valid {
+// 署名に加えて iss, aud, iat, nbf, exp を検証する
+func ValidateJWT(tokens *token.Manager, tokenString string) (*AccessToken, error) {
+	claims, err := tokens.Parse(tokenString)
+	if err != nil {
 		return nil, err
 	}
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, jwt.ErrInvalidKey
-	}
-	user := &User{
-		ID:    claims["sub"].(string),
-		Email: claims["email"].(string),
-		Name:  claims["name"].(string),
+	sub, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+	jti, _ := claims["jti"].(string)
+	wid, _ := claims["wid"].(string)
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil || sub == "" || jti == "" {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
-	return user, nil
+	return &AccessToken{
+		User: User{
+			ID:                sub,
+			Email:             email,
+			Name:              name,
+			ActiveWorkspaceID: wid,
+		},
+		JTI:       jti,
+		ExpiresAt: exp.Time,
+	}, nil
 }
@@ -1,36 +1,55 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/docquery"
 	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
 	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/search"
 	"gorm.io/gorm"
 )
 
 type GetDocResponse struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Tags      string `json:"tags"`
-	Snippet   string `json:"snippet"`
-	AuthorID  string `json:"author_id"`
-	CreatedAt string `json:"created_at"`
-	EditedAt  string `json:"edited_at"`
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	Snippet   string   `json:"snippet"`
+	AuthorID  string   `json:"author_id"`
+	CreatedAt string   `json:"created_at"`
+	EditedAt  string   `json:"edited_at"`
+	Version   int      `json:"version"`
+	ParentID  *string  `json:"parent_id"` // ワークスペースの最上位にある場合はnull
+	Position  int      `json:"position"`
+	// ルートから親までの祖先。取得・移動したときのみ返し、閲覧できない祖先は含めない
+	Breadcrumbs []DocBreadcrumb `json:"breadcrumbs,omitempty"`
+	// 本文の見出しを出現順に並べた目次。取得したときのみ返す
+	Toc []DocHeadingResponse `json:"toc,omitempty"`
+	// contentをサニタイズしたHTMLに変換したもの。取得時にformat=htmlを指定した場合のみ返す
+	HTML *string `json:"html,omitempty"`
 }
 
 type UpdateDocRequest struct {
-	Title   string `json:"title,omitempty"`
-	Content string `json:"content,omitempty"`
-	Tags    string `json:"tags,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Content string   `json:"content,omitempty"`
+	Tags    *TagList `json:"tags,omitempty"`    // 指定しない場合は変更しない。空の配列を指定するとタグをすべて外す
+	Version *int     `json:"version,omitempty"` // If-Matchヘッダーを指定しない場合は必須
 }
 
 type CreateDocRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Tags    string `json:"tags"`
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	Tags     *TagList `json:"tags"`
+	ParentID *string  `json:"parent_id"` // 指定した場合はそのドキュメントの子の末尾に作成する
 }
 
 type ListDocsResponse struct {
@@ -39,12 +58,15 @@ type ListDocsResponse struct {
 }
 
 type DocumentSummary struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Preview   string `json:"preview"`
-	Tags      string `json:"tags"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Preview   string   `json:"preview"`
+	Tags      []string `json:"tags"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	// 以下はqを指定した場合のみ返す
+	Score      *float64 `json:"score,omitempty"`
+	Highlights []string `json:"highlights,omitempty"` // 本文の一致箇所。HTMLエスケープ済みで、一致箇所を<mark>で囲む
 }
 
 type PaginationInfo struct {
@@ -56,10 +78,209 @@ type PaginationInfo struct {
 	HasPrev    bool `json:"has_prev"`
 }
 
-func NewListDocsHandler(db *gorm.DB) gin.HandlerFunc {
+// 一覧の1件あたりに返す一致箇所の最大数
+const maxHighlights = 3
+
+// 検索インデックスから取得する件数の上限。これを超える一致は一覧・件数に含めない
+const maxSearchHits = 1000
+
+func toGetDocResponse(doc domain.Doc) GetDocResponse {
+	return GetDocResponse{
+		ID:        doc.ID().String(),
+		Title:     doc.Title().String(),
+		Content:   doc.Content().String(),
+		Tags:      doc.Tags().Values(),
+		Snippet:   doc.Snippet().String(),
+		AuthorID:  doc.AuthorId().String(),
+		CreatedAt: doc.CreatedAt().String(),
+		EditedAt:  doc.EditedAt().String(),
+		Version:   doc.Version(),
+		ParentID:  docParentID(doc),
+		Position:  doc.Position(),
+	}
+}
+
+func docETag(doc domain.Doc) string {
+	return `"` + strconv.Itoa(doc.Version()) + `"`
+}
+
+// 更新・削除の前提となる版数を、If-Matchヘッダー、リクエストボディ、versionクエリの順に取得する
+// If-Matchに現在の版数か*が含まれる場合は現在の版数を返す
+// 指定がない場合は428、形式が不正な場合は400を書き込み、falseを返す
+func expectedDocVersion(c *gin.Context, doc domain.Doc, bodyVersion *int) (int, bool) {
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		expected := -1
+		for _, tag := range strings.Split(ifMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" {
+				return doc.Version(), true
+			}
+			// If-Matchは強い比較のため、弱いETagは一致しないものとして扱う
+			if strings.HasPrefix(tag, "W/") {
+				continue
+			}
+			unquoted, err := strconv.Unquote(tag)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+				return 0, false
+			}
+			version, err := strconv.Atoi(unquoted)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+				return 0, false
+			}
+			if version == doc.Version() {
+				return version, true
+			}
+			if expected < 0 {
+				expected = version
+			}
+		}
+		return expected, true
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true
+	}
+	if versionStr := c.Query("version"); versionStr != "" {
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "version must be an integer"})
+			return 0, false
+		}
+		return version, true
+	}
+	c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header or version is required"})
+	return 0, false
+}
+
+// 他のユーザーによって更新されていた場合に、最新のドキュメントとともに412を返す
+func respondDocVersionConflict(c *gin.Context, docRepo *gormrepo.DocRepository, docID domain.ID) {
+	current, err := docRepo.Find(docID)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+		return
+	}
+	c.Header("ETag", docETag(current))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "Document has been modified by another user",
+		"current": toGetDocResponse(current),
+	})
+}
+
+func newDocPolicy(db *gorm.DB, ctx context.Context) *authz.DocPolicy {
+	return authz.NewDocPolicy(
+		gormrepo.NewDocPermissionRepository(db, ctx),
+		gormrepo.NewWorkspaceMemberRepository(db, ctx),
+	)
+}
+
+// ドキュメントを取得し、認証ユーザーがactionを実行できるか判定する
+// 実行できない場合はエラーレスポンスを書き込み、falseを返す
+func findAuthorizedDoc(c *gin.Context, docRepo *gormrepo.DocRepository, policy *authz.DocPolicy, action authz.Action) (domain.Doc, bool) {
+	userID, err := domain.NewID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return domain.Doc{}, false
+	}
+	docID, err := domain.NewID(c.Param("doc_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return domain.Doc{}, false
+	}
+
+	doc, err := docRepo.Find(docID)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return domain.Doc{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+		return domain.Doc{}, false
+	}
+
+	if !authorizeDoc(c, policy, userID, doc, action) {
+		return domain.Doc{}, false
+	}
+	return doc, true
+}
+
+// 認証ユーザーがドキュメントに対してactionを実行できるか判定する
+// 実行できない場合はエラーレスポンスを書き込み、falseを返す
+func authorizeDoc(c *gin.Context, policy *authz.DocPolicy, userID domain.ID, doc domain.Doc, action authz.Action) bool {
+	if err := policy.Authorize(userID, doc, action); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+		case errors.Is(err, domain.ErrEntityNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+		}
+		return false
+	}
+	return true
+}
+
+// 検索インデックスを更新する。indexがnilの場合は何もしない
+// データベースが正であり再構築で復旧できるため、失敗してもリクエストは失敗させない
+func indexDoc(index domain.SearchIndex, doc domain.Doc) {
+	if index == nil {
+		return
+	}
+	if err := index.Index(doc); err != nil {
+		slog.Error("failed to index document", slog.Any("error", err), slog.String("doc_id", doc.ID().String()))
+	}
+}
+
+func unindexDoc(index domain.SearchIndex, docID domain.ID) {
+	if index == nil {
+		return
+	}
+	if err := index.Delete(docID); err != nil {
+		slog.Error("failed to remove document from search index", slog.Any("error", err), slog.String("doc_id", docID.String()))
+	}
+}
+
+// 検索クエリのauthor:に指定したメールアドレスを、ワークスペースのメンバーに限って解決する
+// メンバー以外は存在しないユーザーと同じ扱いにし、メールアドレスの登録有無がわからないようにする
+func newWorkspaceAuthorResolver(c *gin.Context, db *gorm.DB, workspaceID domain.ID) docquery.AuthorResolver {
+	return func(value string) (domain.ID, error) {
+		email, err := domain.NewEmail(value)
+		if err != nil {
+			return domain.ID{}, domain.ErrEntityNotFound
+		}
+		user, err := gormrepo.NewUserRepository(db, c.Request.Context()).FindByEmail(email)
+		if err != nil {
+			return domain.ID{}, err
+		}
+		if _, err := gormrepo.NewWorkspaceMemberRepository(db, c.Request.Context()).Find(workspaceID, user.ID()); err != nil {
+			return domain.ID{}, err
+		}
+		return user.ID(), nil
+	}
+}
+
+// ドキュメント一覧
+// indexを指定した場合は、qの全文検索を検索インデックスで行う。nilの場合はデータベースの全文検索インデックスを使う
+func NewListDocsHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
 
+		viewerID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		workspaceID, err := activeWorkspaceID(c, db, viewerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve documents"})
+			return
+		}
+
 		page, err := domain.NewPage(1)
 		if pageStr := c.Query("page"); pageStr != "" {
 			if pageInt, err := strconv.Atoi(pageStr); err == nil && pageInt > 0 {
@@ -74,7 +295,35 @@ func NewListDocsHandler(db *gorm.DB) gin.HandlerFunc {
 			}
 		}
 
+		// 検索クエリ（tag:API -tag:draft "error handling" など）。単独の語・フレーズはqとあわせて全文検索する
+		var filter domain.DocFilter
+		searchInput := c.Query("q")
+		if queryStr := c.Query("query"); queryStr != "" {
+			parsed, err := docquery.Parse(queryStr, viewerID, newWorkspaceAuthorResolver(c, db, workspaceID))
+			var queryErr *docquery.Error
+			if errors.As(err, &queryErr) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": queryErr.Error(), "position": queryErr.Position})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve documents"})
+				return
+			}
+			filter = parsed.Filter
+			searchInput = strings.TrimSpace(searchInput + " " + parsed.Text)
+		}
+
+		text, err := domain.NewSearchText(searchInput)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 全文検索の場合は、並び順を指定しなければ関連度順にする
 		sortBy := domain.DefaultSortBy()
+		if !text.IsEmpty() {
+			sortBy, _ = domain.NewSortBy(domain.SortByRelevance)
+		}
 		if sortByStr := c.Query("sort_by"); sortByStr != "" {
 			if sb, err := domain.NewSortBy(sortByStr); err == nil {
 				sortBy = sb
@@ -88,7 +337,17 @@ func NewListDocsHandler(db *gorm.DB) gin.HandlerFunc {
 			}
 		}
 
-		tags, _ := domain.NewTags(c.Query("tags"))
+		// tagsはカンマ区切りで、タグ名の完全一致で絞り込む。tag_mode=orの場合はいずれかのタグを含むものを対象にする
+		tags, err := domain.NewTags(c.Query("tags"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tagMode := c.DefaultQuery("tag_mode", "and")
+		if tagMode != "and" && tagMode != "or" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag_mode must be and or or"})
+			return
+		}
 
 		createdFrom, err := domain.NewDateFilter(c.Query("created_from"))
 		if err != nil {
@@ -122,24 +381,70 @@ func NewListDocsHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		query := domain.NewDocsQuery(page, limit, sortBy, sortOrder, tags, createdRange, updatedRange)
+		sharedOnly := false
+		if sharedStr := c.Query("shared_with_me"); sharedStr != "" {
+			if sharedOnly, err = strconv.ParseBool(sharedStr); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "shared_with_me must be true or false"})
+				return
+			}
+		}
 
-		docs, total, err := docRepo.FindDocs(query)
+		if !tags.IsEmpty() {
+			conditions := make([]domain.DocCondition, 0, len(tags.Values()))
+			for _, tag := range tags.Values() {
+				conditions = append(conditions, domain.NewTagCondition(tag, false))
+			}
+			if tagMode == "or" {
+				filter = filter.And(conditions...)
+			} else {
+				for _, condition := range conditions {
+					filter = filter.And(condition)
+				}
+			}
+		}
+
+		query := domain.NewDocsQuery(viewerID, workspaceID, page, limit, sortBy, sortOrder, createdRange, updatedRange, sharedOnly, text, filter)
+		if index != nil && !text.IsEmpty() {
+			// 閲覧できないドキュメントで上限が埋まらないよう、FindDocsと同じ範囲で検索する
+			scope := domain.NewWorkspaceSearchScope(workspaceID)
+			if sharedOnly {
+				sharedDocIDs, err := gormrepo.NewDocPermissionRepository(db, c.Request.Context()).FindDocIDsByUserID(viewerID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve documents"})
+					return
+				}
+				scope = domain.NewDocsSearchScope(sharedDocIDs)
+			}
+			hits, err := index.Search(text, scope, maxSearchHits)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve documents"})
+				return
+			}
+			query = query.WithSearchHits(hits)
+		}
+
+		matches, total, err := docRepo.FindDocs(query)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve documents"})
 			return
 		}
 
-		documentSummaries := make([]DocumentSummary, len(docs))
-		for i, doc := range docs {
+		documentSummaries := make([]DocumentSummary, len(matches))
+		for i, match := range matches {
+			doc := match.Doc()
 			documentSummaries[i] = DocumentSummary{
 				ID:        doc.ID().String(),
 				Title:     doc.Title().String(),
 				Preview:   doc.Snippet().String(),
-				Tags:      doc.Tags().String(),
+				Tags:      doc.Tags().Values(),
 				CreatedAt: doc.CreatedAt().String(),
 				UpdatedAt: doc.EditedAt().String(),
 			}
+			if !text.IsEmpty() {
+				score := match.Score()
+				documentSummaries[i].Score = &score
+				documentSummaries[i].Highlights = search.Highlight(doc.Content().Value(), search.HighlightTerms(text.Value()), maxHighlights)
+			}
 		}
 
 		totalPages := (total + limit.Value() - 1) / limit.Value()
@@ -163,7 +468,7 @@ func NewListDocsHandler(db *gorm.DB) gin.HandlerFunc {
 }
 
 // ドキュメント作成
-func NewCreateDocHandler(db *gorm.DB) gin.HandlerFunc {
+func NewCreateDocHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
 
@@ -182,12 +487,11 @@ func NewCreateDocHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 		content := domain.NewContent(req.Content)
-		tags, err := domain.NewTags(req.Tags)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+		var tags domain.Tags
+		if req.Tags != nil {
+			tags = req.Tags.Tags()
 		}
-		snippet, _ := domain.NewDocSnippet(domain.ExtractSnippet(req.Content))
+		snippet, _ := domain.NewDocSnippet(markdown.Snippet(req.Title, req.Content, domain.MaxDocSnippetLength))
 		authorIDStr, exists := c.Get("user_id")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -198,9 +502,36 @@ func NewCreateDocHandler(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
 			return
 		}
+		workspaceID, err := activeWorkspaceID(c, db, authorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create document"})
+			return
+		}
+		// ワークスペースから外されたユーザーは、そのワークスペースにドキュメントを作成できない
+		if _, err := gormrepo.NewWorkspaceMemberRepository(db, c.Request.Context()).Find(workspaceID, authorID); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of the active workspace"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create document"})
+			return
+		}
+		var parentID domain.ID
+		if req.ParentID != nil && *req.ParentID != "" {
+			parent, ok := findAuthorizedParentDoc(c, docRepo, newDocPolicy(db, c.Request.Context()), authorID, *req.ParentID)
+			if !ok {
+				return
+			}
+			if parent.WorkspaceID() != workspaceID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Parent document must be in the active workspace"})
+				return
+			}
+			parentID = parent.ID()
+		}
 
 		doc := domain.NewDoc(
 			domain.GenerateID(),
+			workspaceID,
 			title,
 			content,
 			tags,
@@ -208,69 +539,72 @@ func NewCreateDocHandler(db *gorm.DB) gin.HandlerFunc {
 			authorID,
 			domain.NewCreatedAtNow(),
 			domain.NewEditedAtNow(),
+			authorID,
+			0,
+			parentID,
+			0,
 		)
 		saved, err := docRepo.Save(doc)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create document"})
 			return
 		}
+		indexDoc(index, saved)
 
-		resp := GetDocResponse{
-			ID:        saved.ID().String(),
-			Title:     saved.Title().String(),
-			Content:   saved.Content().String(),
-			Tags:      saved.Tags().String(),
-			Snippet:   saved.Snippet().String(),
-			AuthorID:  saved.AuthorId().String(),
-			CreatedAt: saved.CreatedAt().String(),
-			EditedAt:  saved.EditedAt().String(),
-		}
+		c.Header("ETag", docETag(saved))
+		resp := toGetDocResponse(saved)
 		c.JSON(http.StatusCreated, resp)
 	}
 }
 
-func NewGetDocHandler(db *gorm.DB) gin.HandlerFunc {
+// format=html の場合はcontentに加えて、HTMLに変換したものを返す
+func NewGetDocHandler(db *gorm.DB, renderCache *markdown.Cache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
 
-		docIDStr := c.Param("doc_id")
-		docID, err := domain.NewID(docIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		format := c.DefaultQuery("format", "markdown")
+		if format != "markdown" && format != "html" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'markdown' or 'html'"})
 			return
 		}
 
-		doc, err := docRepo.Find(docID)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
 			return
 		}
 
-		resp := GetDocResponse{
-			ID:        doc.ID().String(),
-			Title:     doc.Title().String(),
-			Content:   doc.Content().String(),
-			Tags:      doc.Tags().String(),
-			Snippet:   doc.Snippet().String(),
-			AuthorID:  doc.AuthorId().String(),
-			CreatedAt: doc.CreatedAt().String(),
-			EditedAt:  doc.EditedAt().String(),
+		c.Header("ETag", docETag(doc))
+		resp := toGetDocResponse(doc)
+		breadcrumbs, err := docBreadcrumbs(c, docRepo, policy, doc)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+			return
+		}
+		resp.Breadcrumbs = breadcrumbs
+		headings, err := docRepo.FindHeadings(doc.ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+			return
+		}
+		resp.Toc = toDocHeadingResponses(headings)
+		if format == "html" {
+			html, err := renderCache.Render(doc.Content().Value())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render document"})
+				return
+			}
+			resp.HTML = &html
 		}
 		c.JSON(http.StatusOK, resp)
 	}
 }
 
 // ドキュメント更新
-func NewUpdateDocHandler(db *gorm.DB) gin.HandlerFunc {
+func NewUpdateDocHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
-
-		docIDStr := c.Param("doc_id")
-		docID, err := domain.NewID(docIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
-			return
-		}
+		policy := newDocPolicy(db, c.Request.Context())
 
 		var req UpdateDocRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -278,9 +612,21 @@ func NewUpdateDocHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		doc, err := docRepo.Find(docID)
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionUpdate)
+		if !ok {
+			return
+		}
+		editorID, err := domain.NewID(c.GetString("user_id"))
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		version, ok := expectedDocVersion(c, doc, req.Version)
+		if !ok {
+			return
+		}
+		if version != doc.Version() {
+			respondDocVersionConflict(c, docRepo, doc.ID())
 			return
 		}
 
@@ -291,23 +637,31 @@ func NewUpdateDocHandler(db *gorm.DB) gin.HandlerFunc {
 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
+			// 本文の先頭の見出しがタイトルと同じかどうかで概要が変わる
+			snippet, _ := domain.NewDocSnippet(markdown.Snippet(title.Value(), doc.Content().Value(), domain.MaxDocSnippetLength))
 			updatedDoc = domain.NewDoc(
 				doc.ID(),
+				doc.WorkspaceID(),
 				title,
 				doc.Content(),
 				doc.Tags(),
-				doc.Snippet(),
+				snippet,
 				doc.AuthorId(),
 				doc.CreatedAt(),
 				domain.NewEditedAtNow(),
+				editorID,
+				doc.Version(),
+				doc.ParentID(),
+				doc.Position(),
 			)
 		}
 
 		if req.Content != "" {
 			content := domain.NewContent(req.Content)
-			snippet, _ := domain.NewDocSnippet(domain.ExtractSnippet(req.Content))
+			snippet, _ := domain.NewDocSnippet(markdown.Snippet(updatedDoc.Title().Value(), req.Content, domain.MaxDocSnippetLength))
 			updatedDoc = domain.NewDoc(
 				updatedDoc.ID(),
+				updatedDoc.WorkspaceID(),
 				updatedDoc.Title(),
 				content,
 				updatedDoc.Tags(),
@@ -315,64 +669,86 @@ func NewUpdateDocHandler(db *gorm.DB) gin.HandlerFunc {
 				updatedDoc.AuthorId(),
 				updatedDoc.CreatedAt(),
 				domain.NewEditedAtNow(),
+				editorID,
+				doc.Version(),
+				doc.ParentID(),
+				doc.Position(),
 			)
 		}
 
-		if req.Tags != "" {
-			tags, err := domain.NewTags(req.Tags)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-				return
-			}
+		if req.Tags != nil && !req.Tags.emptyString {
 			updatedDoc = domain.NewDoc(
 				updatedDoc.ID(),
+				updatedDoc.WorkspaceID(),
 				updatedDoc.Title(),
 				updatedDoc.Content(),
-				tags,
+				req.Tags.Tags(),
 				updatedDoc.Snippet(),
 				updatedDoc.AuthorId(),
 				updatedDoc.CreatedAt(),
 				domain.NewEditedAtNow(),
+				editorID,
+				doc.Version(),
+				doc.ParentID(),
+				doc.Position(),
 			)
 		}
 
 		saved, err := docRepo.Save(updatedDoc)
 		if err != nil {
+			if errors.Is(err, domain.ErrVersionConflict) {
+				respondDocVersionConflict(c, docRepo, doc.ID())
+				return
+			}
+			// 取得後にゴミ箱に移された
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document"})
 			return
 		}
-
-		resp := GetDocResponse{
-			ID:        saved.ID().String(),
-			Title:     saved.Title().String(),
-			Content:   saved.Content().String(),
-			Tags:      saved.Tags().String(),
-			Snippet:   saved.Snippet().String(),
-			AuthorID:  saved.AuthorId().String(),
-			CreatedAt: saved.CreatedAt().String(),
-			EditedAt:  saved.EditedAt().String(),
+		indexDoc(index, saved)
+		if saved.Title().Value() != doc.Title().Value() {
+			indexBacklinks(c, db, index, saved.ID())
 		}
+
+		c.Header("ETag", docETag(saved))
+		resp := toGetDocResponse(saved)
 		c.JSON(http.StatusOK, resp)
 	}
 }
 
 // ドキュメント削除
-func NewDeleteDocHandler(db *gorm.DB) gin.HandlerFunc {
+// ゴミ箱に移動し、保持期間内であれば復元できる
+func NewDeleteDocHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
 
-		docIDStr := c.Param("doc_id")
-		docID, err := domain.NewID(docIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionDelete)
+		if !ok {
+			return
+		}
+		version, ok := expectedDocVersion(c, doc, nil)
+		if !ok {
 			return
 		}
 
-		err = docRepo.Delete(docID)
-		if err != nil {
+		if err := docRepo.DeleteWithVersion(doc.ID(), version); err != nil {
+			if errors.Is(err, domain.ErrVersionConflict) {
+				respondDocVersionConflict(c, docRepo, doc.ID())
+				return
+			}
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
 			return
 		}
+		// ゴミ箱のドキュメントは検索対象外のため、インデックスからは削除する。復元時に登録し直す
+		unindexDoc(index, doc.ID())
 
 		c.Status(http.StatusNoContent)
 	}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+type DocOutlink struct {
+	Target string  `json:"target"` // 本文中のリンク先の指定
+	DocID  *string `json:"doc_id"` // リンク切れの場合はnull
+	Title  *string `json:"title"`
+	Broken bool    `json:"broken"`
+}
+
+type ListDocOutlinksResponse struct {
+	Links       []DocOutlink `json:"links"`
+	BrokenCount int          `json:"broken_count"`
+}
+
+type ListDocBacklinksResponse struct {
+	Documents []DocumentSummary `json:"documents"`
+}
+
+// タイトルを変更したドキュメントへのリンクは本文が書き換わるため、リンク元を検索インデックスに反映する
+func indexBacklinks(c *gin.Context, db *gorm.DB, index domain.SearchIndex, docID domain.ID) {
+	if index == nil {
+		return
+	}
+	sources, err := gormrepo.NewDocLinkRepository(db, c.Request.Context()).FindBacklinks(docID)
+	if err != nil {
+		slog.Error("failed to find backlinks to reindex", slog.Any("error", err), slog.String("doc_id", docID.String()))
+		return
+	}
+	for _, source := range sources {
+		indexDoc(index, source)
+	}
+}
+
+// ドキュメントの本文中のリンク
+// 閲覧できないドキュメントへのリンクは、存在を知られないようリンク切れとして返す
+func NewListDocOutlinksHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+		viewerID, _ := domain.NewID(c.GetString("user_id"))
+
+		links, err := gormrepo.NewDocLinkRepository(db, c.Request.Context()).FindOutlinks(doc.ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve links"})
+			return
+		}
+
+		resp := ListDocOutlinksResponse{Links: make([]DocOutlink, len(links))}
+		for i, link := range links {
+			resp.Links[i] = DocOutlink{Target: link.Target(), Broken: true}
+			if !link.IsBroken() {
+				if err := policy.Authorize(viewerID, link.Doc(), authz.ActionRead); err == nil {
+					id, title := link.Doc().ID().String(), link.Doc().Title().String()
+					resp.Links[i] = DocOutlink{Target: link.Target(), DocID: &id, Title: &title}
+					continue
+				} else if !errors.Is(err, domain.ErrEntityNotFound) {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve links"})
+					return
+				}
+			}
+			resp.BrokenCount++
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// ドキュメントにリンクしているドキュメント
+// 認証ユーザーが閲覧できるものをタイトル順に返す
+func NewListDocBacklinksHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+		viewerID, _ := domain.NewID(c.GetString("user_id"))
+
+		sources, err := gormrepo.NewDocLinkRepository(db, c.Request.Context()).FindBacklinks(doc.ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve backlinks"})
+			return
+		}
+
+		resp := ListDocBacklinksResponse{Documents: make([]DocumentSummary, 0, len(sources))}
+		for _, source := range sources {
+			if err := policy.Authorize(viewerID, source, authz.ActionRead); err != nil {
+				if errors.Is(err, domain.ErrEntityNotFound) {
+					continue
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve backlinks"})
+				return
+			}
+			resp.Documents = append(resp.Documents, DocumentSummary{
+				ID:        source.ID().String(),
+				Title:     source.Title().String(),
+				Preview:   source.Snippet().String(),
+				Tags:      source.Tags().Values(),
+				CreatedAt: source.CreatedAt().String(),
+				UpdatedAt: source.EditedAt().String(),
+			})
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+type GrantDocPermissionRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type DocPermissionResponse struct {
+	UserID    string  `json:"user_id"`
+	Email     string  `json:"email"`
+	Name      string  `json:"name"`
+	Role      string  `json:"role"`
+	GrantedBy *string `json:"granted_by"` // 作成者の場合はnull
+	CreatedAt string  `json:"created_at"`
+}
+
+type ListDocPermissionsResponse struct {
+	Permissions []DocPermissionResponse `json:"permissions"`
+}
+
+func toDocPermissionResponse(user domain.User, permission domain.DocPermission) DocPermissionResponse {
+	grantedBy := permission.GrantedBy().String()
+	return DocPermissionResponse{
+		UserID:    user.ID().String(),
+		Email:     user.Email().String(),
+		Name:      user.AuthorName().String(),
+		Role:      permission.Role().String(),
+		GrantedBy: &grantedBy,
+		CreatedAt: permission.CreatedAt().String(),
+	}
+}
+
+// ドキュメントの共有相手一覧
+// 作成者をownerとして先頭に含める
+func NewListDocPermissionsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		permissionRepo := gormrepo.NewDocPermissionRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+
+		permissions, err := permissionRepo.FindByDocID(doc.ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
+			return
+		}
+
+		resp := ListDocPermissionsResponse{Permissions: make([]DocPermissionResponse, 0, len(permissions)+1)}
+		if author, err := userRepo.Find(doc.AuthorId()); err == nil {
+			resp.Permissions = append(resp.Permissions, DocPermissionResponse{
+				UserID:    author.ID().String(),
+				Email:     author.Email().String(),
+				Name:      author.AuthorName().String(),
+				Role:      domain.OwnerDocRole().String(),
+				CreatedAt: doc.CreatedAt().String(),
+			})
+		} else if !errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
+			return
+		}
+		for _, permission := range permissions {
+			user, err := userRepo.Find(permission.UserID())
+			if err != nil {
+				// 退会済みのユーザーは一覧に含めない
+				if errors.Is(err, domain.ErrEntityNotFound) {
+					continue
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
+				return
+			}
+			resp.Permissions = append(resp.Permissions, toDocPermissionResponse(user, permission))
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// ドキュメントの共有
+// 既に共有済みのユーザーを指定した場合は権限を変更する
+func NewGrantDocPermissionHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		permissionRepo := gormrepo.NewDocPermissionRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		var req GrantDocPermissionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		role, err := domain.NewDocRole(req.Role)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		email, err := domain.NewEmail(req.Email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionManagePermissions)
+		if !ok {
+			return
+		}
+		grantedBy, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		user, err := userRepo.FindByEmail(email)
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant permission"})
+			return
+		}
+		if user.ID() == doc.AuthorId() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The author of the document is always an owner"})
+			return
+		}
+
+		saved, err := permissionRepo.Save(domain.NewDocPermission(
+			doc.ID(),
+			user.ID(),
+			role,
+			grantedBy,
+			domain.NewCreatedAtNow(),
+		))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant permission"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toDocPermissionResponse(user, saved))
+	}
+}
+
+// ドキュメントの共有解除
+// ownerは任意の共有相手を、それ以外のユーザーは自分自身のみ解除できる
+func NewRevokeDocPermissionHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		permissionRepo := gormrepo.NewDocPermissionRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		targetID, err := domain.NewID(c.Param("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		if targetID != userID {
+			if err := policy.Authorize(userID, doc, authz.ActionManagePermissions); err != nil {
+				if errors.Is(err, domain.ErrForbidden) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke permission"})
+				return
+			}
+		}
+		if targetID == doc.AuthorId() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The author of the document is always an owner"})
+			return
+		}
+
+		if err := permissionRepo.Delete(doc.ID(), targetID); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Permission not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke permission"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
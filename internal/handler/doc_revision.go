@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/diff"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+type DocRevisionSummary struct {
+	ID        string   `json:"id"`
+	Number    int      `json:"number"`
+	Title     string   `json:"title"`
+	Tags      []string `json:"tags"`
+	EditedBy  string   `json:"edited_by"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type GetDocRevisionResponse struct {
+	DocRevisionSummary
+	Content string `json:"content"`
+}
+
+type ListDocRevisionsResponse struct {
+	Revisions []DocRevisionSummary `json:"revisions"`
+}
+
+type DocRevisionDiffResponse struct {
+	FromRevisionID *string `json:"from_revision_id"` // 最初のリビジョンとの比較ではnull
+	ToRevisionID   string  `json:"to_revision_id"`
+	Diff           string  `json:"diff"` // contentのunified diff。差分がない場合は空文字
+}
+
+func toDocRevisionSummary(revision domain.DocRevision) DocRevisionSummary {
+	return DocRevisionSummary{
+		ID:        revision.ID().String(),
+		Number:    revision.Number(),
+		Title:     revision.Title().String(),
+		Tags:      revision.Tags().Values(),
+		EditedBy:  revision.EditedBy().String(),
+		CreatedAt: revision.CreatedAt().String(),
+	}
+}
+
+// パスパラメータのリビジョンを取得する
+func findDocRevision(c *gin.Context, revisionRepo *gormrepo.DocRevisionRepository, doc domain.Doc, param string) (domain.DocRevision, bool) {
+	revisionID, err := domain.NewID(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision ID"})
+		return domain.DocRevision{}, false
+	}
+	revision, err := revisionRepo.Find(doc.ID(), revisionID)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return domain.DocRevision{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revision"})
+		return domain.DocRevision{}, false
+	}
+	return revision, true
+}
+
+// リビジョン一覧
+// 新しい順に返し、一覧ではcontentを省略する
+func NewListDocRevisionsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		revisionRepo := gormrepo.NewDocRevisionRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+
+		revisions, err := revisionRepo.FindByDocID(doc.ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revisions"})
+			return
+		}
+
+		resp := ListDocRevisionsResponse{Revisions: make([]DocRevisionSummary, len(revisions))}
+		for i, revision := range revisions {
+			resp.Revisions[i] = toDocRevisionSummary(revision)
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// リビジョン取得
+func NewGetDocRevisionHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		revisionRepo := gormrepo.NewDocRevisionRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+		revision, ok := findDocRevision(c, revisionRepo, doc, c.Param("rev_id"))
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, GetDocRevisionResponse{
+			DocRevisionSummary: toDocRevisionSummary(revision),
+			Content:            revision.Content().String(),
+		})
+	}
+}
+
+// リビジョン間の差分
+// fromを指定しない場合は直前のリビジョンと比較する
+func NewDiffDocRevisionsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		revisionRepo := gormrepo.NewDocRevisionRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+		to, ok := findDocRevision(c, revisionRepo, doc, c.Param("rev_id"))
+		if !ok {
+			return
+		}
+
+		var from domain.DocRevision
+		hasFrom := true
+		if fromParam := c.Query("from"); fromParam != "" {
+			if from, ok = findDocRevision(c, revisionRepo, doc, fromParam); !ok {
+				return
+			}
+		} else {
+			previous, err := revisionRepo.FindPrevious(to)
+			switch {
+			case err == nil:
+				from = previous
+			case errors.Is(err, domain.ErrEntityNotFound):
+				hasFrom = false
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revision"})
+				return
+			}
+		}
+
+		resp := DocRevisionDiffResponse{ToRevisionID: to.ID().String()}
+		fromName := "/dev/null"
+		if hasFrom {
+			fromID := from.ID().String()
+			resp.FromRevisionID = &fromID
+			fromName = fmt.Sprintf("revisions/%d", from.Number())
+		}
+		unified, err := diff.Unified(
+			fromName,
+			fmt.Sprintf("revisions/%d", to.Number()),
+			from.Content().Value(),
+			to.Content().Value(),
+			diff.DefaultContext,
+		)
+		if err != nil {
+			if errors.Is(err, diff.ErrTooLarge) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Revisions are too large or too different to diff"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to diff revisions"})
+			return
+		}
+		resp.Diff = unified
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// リビジョンの復元
+// 履歴は書き換えず、リビジョンの内容で新しいリビジョンを作成する
+func NewRestoreDocRevisionHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		revisionRepo := gormrepo.NewDocRevisionRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionUpdate)
+		if !ok {
+			return
+		}
+		revision, ok := findDocRevision(c, revisionRepo, doc, c.Param("rev_id"))
+		if !ok {
+			return
+		}
+		editorID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		snippet, _ := domain.NewDocSnippet(markdown.Snippet(revision.Title().Value(), revision.Content().Value(), domain.MaxDocSnippetLength))
+		saved, err := docRepo.Save(domain.NewDoc(
+			doc.ID(),
+			doc.WorkspaceID(),
+			revision.Title(),
+			revision.Content(),
+			revision.Tags(),
+			snippet,
+			doc.AuthorId(),
+			doc.CreatedAt(),
+			domain.NewEditedAtNow(),
+			editorID,
+			doc.Version(),
+			doc.ParentID(),
+			doc.Position(),
+		))
+		if err != nil {
+			if errors.Is(err, domain.ErrVersionConflict) {
+				respondDocVersionConflict(c, docRepo, doc.ID())
+				return
+			}
+			// 取得後にゴミ箱に移された
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+			return
+		}
+		indexDoc(index, saved)
+		if saved.Title().Value() != doc.Title().Value() {
+			indexBacklinks(c, db, index, saved.ID())
+		}
+
+		c.Header("ETag", docETag(saved))
+		c.JSON(http.StatusOK, toGetDocResponse(saved))
+	}
+}
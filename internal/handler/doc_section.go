@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+type DocHeadingResponse struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"` // format=htmlで返す見出しのidと一致する
+}
+
+type GetDocSectionResponse struct {
+	DocHeadingResponse
+	Content string `json:"content"` // 見出しの行から次の同じかより上位の見出しの前までのMarkdown
+}
+
+func toDocHeadingResponses(headings []domain.DocHeading) []DocHeadingResponse {
+	resp := make([]DocHeadingResponse, len(headings))
+	for i, heading := range headings {
+		resp[i] = DocHeadingResponse{
+			Level:  heading.Level(),
+			Text:   heading.Text(),
+			Anchor: heading.Anchor(),
+		}
+	}
+	return resp
+}
+
+// 目次のアンカーで指定した節のMarkdown
+func NewGetDocSectionHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionRead)
+		if !ok {
+			return
+		}
+
+		heading, content, ok := markdown.Section(doc.Content().Value(), c.Param("anchor"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
+			return
+		}
+
+		c.Header("ETag", docETag(doc))
+		c.JSON(http.StatusOK, GetDocSectionResponse{
+			DocHeadingResponse: DocHeadingResponse{
+				Level:  heading.Level,
+				Text:   heading.Text,
+				Anchor: heading.Anchor,
+			},
+			Content: content,
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+type DocBreadcrumb struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type DocTreeNodeResponse struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Position    int    `json:"position"`
+	HasChildren bool   `json:"has_children"` // 子を取得するには、このIDをparent_idに指定する
+}
+
+type DocTreeResponse struct {
+	ParentID *string               `json:"parent_id"`
+	Nodes    []DocTreeNodeResponse `json:"nodes"`
+}
+
+type MoveDocRequest struct {
+	ParentID *string `json:"parent_id"` // nullまたは省略した場合はワークスペースの最上位に移動する
+	Position *int    `json:"position"`  // 移動先の兄弟の中での位置。省略した場合は末尾
+}
+
+func docParentID(doc domain.Doc) *string {
+	if doc.ParentID().IsNil() {
+		return nil
+	}
+	parentID := doc.ParentID().String()
+	return &parentID
+}
+
+// ドキュメントの祖先のうち、認証ユーザーが閲覧できるものをルートから順に返す
+func docBreadcrumbs(c *gin.Context, docRepo *gormrepo.DocRepository, policy *authz.DocPolicy, doc domain.Doc) ([]DocBreadcrumb, error) {
+	if doc.ParentID().IsNil() {
+		return nil, nil
+	}
+	userID, err := domain.NewID(c.GetString("user_id"))
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := docRepo.FindAncestors(doc.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	breadcrumbs := make([]DocBreadcrumb, 0, len(ancestors))
+	for _, ancestor := range ancestors {
+		if err := policy.Authorize(userID, ancestor, authz.ActionRead); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		breadcrumbs = append(breadcrumbs, DocBreadcrumb{ID: ancestor.ID().String(), Title: ancestor.Title().String()})
+	}
+	return breadcrumbs, nil
+}
+
+// 作成先・移動先の親のドキュメントを取得し、認証ユーザーが子を追加できるか判定する
+// 子の追加は親の編集として扱う。追加できない場合はエラーレスポンスを書き込み、falseを返す
+func findAuthorizedParentDoc(c *gin.Context, docRepo *gormrepo.DocRepository, policy *authz.DocPolicy, userID domain.ID, value string) (domain.Doc, bool) {
+	parentID, err := domain.NewID(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent document ID"})
+		return domain.Doc{}, false
+	}
+	parent, err := docRepo.Find(parentID)
+	if err == nil {
+		err = policy.Authorize(userID, parent, authz.ActionUpdate)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrEntityNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent document not found"})
+		case errors.Is(err, domain.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add documents under the parent"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve parent document"})
+		}
+		return domain.Doc{}, false
+	}
+	return parent, true
+}
+
+// ドキュメントの階層
+// parent_idの子を並び順に返す。省略した場合はアクティブなワークスペースの最上位を返す
+func NewGetDocTreeHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		viewerID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var workspaceID, parentID domain.ID
+		if parentIDStr := c.Query("parent_id"); parentIDStr != "" {
+			if parentID, err = domain.NewID(parentIDStr); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent document ID"})
+				return
+			}
+			parent, err := docRepo.Find(parentID)
+			if err != nil {
+				if errors.Is(err, domain.ErrEntityNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document tree"})
+				return
+			}
+			if !authorizeDoc(c, policy, viewerID, parent, authz.ActionRead) {
+				return
+			}
+			workspaceID = parent.WorkspaceID()
+		} else if workspaceID, err = activeWorkspaceID(c, db, viewerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document tree"})
+			return
+		}
+
+		children, err := docRepo.FindChildren(viewerID, workspaceID, parentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document tree"})
+			return
+		}
+
+		resp := DocTreeResponse{Nodes: make([]DocTreeNodeResponse, len(children))}
+		if !parentID.IsNil() {
+			value := parentID.String()
+			resp.ParentID = &value
+		}
+		for i, child := range children {
+			resp.Nodes[i] = DocTreeNodeResponse{
+				ID:          child.Doc().ID().String(),
+				Title:       child.Doc().Title().String(),
+				Position:    child.Doc().Position(),
+				HasChildren: child.HasChildren(),
+			}
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// ドキュメントの移動・並べ替え
+// 同じ親を指定すると兄弟の中での位置だけを変更する。内容の変更ではないため版数は変わらない
+func NewMoveDocHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionUpdate)
+		if !ok {
+			return
+		}
+		userID, _ := domain.NewID(c.GetString("user_id"))
+
+		var req MoveDocRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		position := math.MaxInt
+		if req.Position != nil {
+			if *req.Position < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Position must be zero or greater"})
+				return
+			}
+			position = *req.Position
+		}
+
+		var parentID domain.ID
+		if req.ParentID != nil && *req.ParentID != "" {
+			parent, ok := findAuthorizedParentDoc(c, docRepo, policy, userID, *req.ParentID)
+			if !ok {
+				return
+			}
+			if parent.WorkspaceID() != doc.WorkspaceID() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Documents cannot be moved to another workspace"})
+				return
+			}
+			parentID = parent.ID()
+		}
+
+		moved, err := docRepo.Move(doc.ID(), parentID, position)
+		if err != nil {
+			switch {
+			case errors.Is(err, domain.ErrDocCycle):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A document cannot be moved under itself or its descendants"})
+			case errors.Is(err, domain.ErrEntityNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move document"})
+			}
+			return
+		}
+
+		resp := toGetDocResponse(moved)
+		breadcrumbs, err := docBreadcrumbs(c, docRepo, policy, moved)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+			return
+		}
+		resp.Breadcrumbs = breadcrumbs
+		c.Header("ETag", docETag(moved))
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,518 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/password"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"github.com/iotassss/gizzmd/internal/token"
+	"github.com/iotassss/gizzmd/internal/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer        = "GizzMD"
+	mfaChallengeTTL   = 5 * time.Minute
+	maxMFAAttempts    = 5
+	recoveryCodeCount = 10
+	// 2要素目の検証にmaxMFAAttempts回続けて失敗した場合に、受け付けない期間
+	secondFactorLockout = 15 * time.Minute
+)
+
+var (
+	errSecondFactorInvalid = errors.New("second factor is invalid")
+	errSecondFactorLocked  = errors.New("second factor is locked")
+)
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// 認証アプリのコードかリカバリーコードのどちらか一方を指定する
+type SecondFactorRequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	SecondFactorRequest
+}
+
+type MFAStatusResponse struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type EnrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type EnableTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password,omitempty"` // パスワード未設定のユーザー（OIDCのみ）は不要
+	SecondFactorRequest
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// 有効な2段階認証の設定を返す。未設定または登録途中の場合はErrEntityNotFound
+func findEnabledTOTP(totpRepo *gormrepo.TOTPCredentialRepository, userID domain.ID) (domain.TOTPCredential, error) {
+	credential, err := totpRepo.Find(userID)
+	if err != nil {
+		return domain.TOTPCredential{}, err
+	}
+	if !credential.Enabled() {
+		return domain.TOTPCredential{}, domain.ErrEntityNotFound
+	}
+	return credential, nil
+}
+
+// 認証アプリのコードまたはリカバリーコードを検証し、使用済みにする
+func verifySecondFactor(db *gorm.DB, ctx context.Context, credential domain.TOTPCredential, req SecondFactorRequest) error {
+	switch {
+	case req.Code != "":
+		step, ok := totp.Validate(credential.Secret(), req.Code, time.Now(), credential.LastUsedStep())
+		if !ok {
+			return errSecondFactorInvalid
+		}
+		err := gormrepo.NewTOTPCredentialRepository(db, ctx).MarkStepUsed(credential.UserID(), step)
+		if errors.Is(err, domain.ErrTokenInvalid) {
+			return errSecondFactorInvalid
+		}
+		return err
+	case req.RecoveryCode != "":
+		codeHash := securetoken.Hash(totp.NormalizeRecoveryCode(req.RecoveryCode))
+		err := gormrepo.NewRecoveryCodeRepository(db, ctx).Consume(credential.UserID(), codeHash)
+		if errors.Is(err, domain.ErrTokenInvalid) {
+			return errSecondFactorInvalid
+		}
+		if err == nil {
+			slog.Info("recovery code used", slog.String("user_id", credential.UserID().String()))
+		}
+		return err
+	default:
+		return errSecondFactorInvalid
+	}
+}
+
+// 失敗回数をユーザーごとに数えて2要素目を検証する
+// チャレンジを取り直したりセッションを奪われたりしても6桁のコードを総当たりされないよう、
+// ログイン・ログイン後の操作を通じてmaxMFAAttempts回続けて失敗するとしばらく受け付けなくする
+func verifyLimitedSecondFactor(db *gorm.DB, ctx context.Context, credential domain.TOTPCredential, req SecondFactorRequest) error {
+	totpRepo := gormrepo.NewTOTPCredentialRepository(db, ctx)
+	if credential.FailedAttempts() >= maxMFAAttempts && time.Since(credential.LastFailedAt()) < secondFactorLockout {
+		return errSecondFactorLocked
+	}
+	if err := verifySecondFactor(db, ctx, credential, req); err != nil {
+		if !errors.Is(err, errSecondFactorInvalid) {
+			return err
+		}
+		attempts, err := totpRepo.RecordFailure(credential.UserID(), secondFactorLockout)
+		if err != nil {
+			return err
+		}
+		if attempts >= maxMFAAttempts {
+			slog.Warn("second factor attempts exceeded", slog.String("user_id", credential.UserID().String()))
+		}
+		return errSecondFactorInvalid
+	}
+	return totpRepo.ResetFailures(credential.UserID())
+}
+
+// リカバリーコードを新しく発行し、既存のコードを無効にする。返り値は平文のコード
+func issueRecoveryCodes(recoveryRepo *gormrepo.RecoveryCodeRepository, userID domain.ID) ([]string, error) {
+	plain, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]domain.RecoveryCode, len(plain))
+	for i, code := range plain {
+		codes[i] = domain.NewRecoveryCode(domain.GenerateID(), userID, securetoken.Hash(totp.NormalizeRecoveryCode(code)))
+	}
+	if err := recoveryRepo.Replace(userID, codes); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// 2段階認証のチャレンジを発行する
+// パスワード認証に成功したことを示すだけのトークンで、アクセストークンとしては使えない
+func issueMFAChallenge(challengeRepo *gormrepo.MFAChallengeRepository, userID domain.ID) (MFAChallengeResponse, error) {
+	plain, hash, err := securetoken.Generate()
+	if err != nil {
+		return MFAChallengeResponse{}, err
+	}
+	challenge := domain.NewMFAChallenge(
+		domain.GenerateID(),
+		userID,
+		hash,
+		0,
+		domain.NewExpiresAtAfter(mfaChallengeTTL),
+	)
+	if _, err := challengeRepo.Save(challenge); err != nil {
+		return MFAChallengeResponse{}, err
+	}
+	return MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    plain,
+		ExpiresIn:   int(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// 2段階認証ログイン
+// ログインで返されたmfa_tokenと2要素目を検証し、トークンを発行する
+func NewLoginMFAHandler(db *gorm.DB, tokens *token.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		totpRepo := gormrepo.NewTOTPCredentialRepository(db, c.Request.Context())
+		challengeRepo := gormrepo.NewMFAChallengeRepository(db, c.Request.Context())
+		refreshRepo := gormrepo.NewRefreshTokenRepository(db, c.Request.Context())
+
+		var req LoginMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+
+		challenge, err := challengeRepo.FindByHash(securetoken.Hash(req.MFAToken))
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "MFA token is invalid or has expired"})
+				return
+			}
+			slog.Error("failed to find mfa challenge", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to authenticate user"})
+			return
+		}
+		if challenge.ExpiresAt().IsExpired() || challenge.Attempts() >= maxMFAAttempts {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "MFA token is invalid or has expired"})
+			return
+		}
+
+		credential, err := findEnabledTOTP(totpRepo, challenge.UserID())
+		if err != nil {
+			// チャレンジ発行後に2段階認証が無効化された場合も、ログインをやり直させる
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "MFA token is invalid or has expired"})
+				return
+			}
+			slog.Error("failed to find totp credential", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to authenticate user"})
+			return
+		}
+
+		if err := verifyLimitedSecondFactor(db, c.Request.Context(), credential, req.SecondFactorRequest); err != nil {
+			if errors.Is(err, errSecondFactorLocked) {
+				c.JSON(http.StatusTooManyRequests, ErrorResponse{Message: "Too many incorrect codes. Try again later"})
+				return
+			}
+			if !errors.Is(err, errSecondFactorInvalid) {
+				slog.Error("failed to verify second factor", slog.Any("error", err))
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to authenticate user"})
+				return
+			}
+			// ユーザーごとの失敗回数に加えて、失敗が続いたチャレンジは使えなくする
+			attempts, err := challengeRepo.RecordFailure(challenge.ID())
+			if err != nil && !errors.Is(err, domain.ErrTokenInvalid) {
+				slog.Error("failed to record mfa failure", slog.Any("error", err))
+			}
+			if attempts >= maxMFAAttempts {
+				slog.Warn("mfa attempts exceeded", slog.String("user_id", challenge.UserID().String()))
+			}
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "The provided code is incorrect"})
+			return
+		}
+
+		if err := challengeRepo.Consume(challenge.ID()); err != nil {
+			if errors.Is(err, domain.ErrTokenInvalid) {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "MFA token is invalid or has expired"})
+				return
+			}
+			slog.Error("failed to consume mfa challenge", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to authenticate user"})
+			return
+		}
+
+		user, err := userRepo.Find(challenge.UserID())
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "MFA token is invalid or has expired"})
+			return
+		}
+		resp, err := issueLoginTokens(tokens, refreshRepo, user)
+		if err != nil {
+			slog.Error("failed to issue tokens", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// 2段階認証の設定状況
+func NewMFAStatusHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		totpRepo := gormrepo.NewTOTPCredentialRepository(db, c.Request.Context())
+		recoveryRepo := gormrepo.NewRecoveryCodeRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		var resp MFAStatusResponse
+		if _, err := findEnabledTOTP(totpRepo, userID); err == nil {
+			resp.TOTPEnabled = true
+			if resp.RecoveryCodesRemaining, err = recoveryRepo.CountUnused(userID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA status"})
+				return
+			}
+		} else if !errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve MFA status"})
+			return
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// 2段階認証の登録開始
+// シークレットを発行するが、認証アプリのコードを検証するまでは有効にならない
+func NewEnrollTOTPHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		totpRepo := gormrepo.NewTOTPCredentialRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		user, err := userRepo.Find(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		if _, err := findEnabledTOTP(totpRepo, userID); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		} else if !errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll two-factor authentication"})
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll two-factor authentication"})
+			return
+		}
+		// 登録途中のシークレットがあれば置き換える
+		if _, err := totpRepo.Save(domain.NewTOTPCredential(userID, secret, false, 0, 0, time.Time{})); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll two-factor authentication"})
+			return
+		}
+
+		c.JSON(http.StatusOK, EnrollTOTPResponse{
+			Secret:     secret,
+			OTPAuthURI: totp.URI(totpIssuer, user.Email().Value(), secret),
+		})
+	}
+}
+
+// 2段階認証の有効化
+// 認証アプリのコードを検証し、リカバリーコードを発行する
+func NewEnableTOTPHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		totpRepo := gormrepo.NewTOTPCredentialRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		var req EnableTOTPRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		credential, err := totpRepo.Find(userID)
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication enrollment has not been started"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+			return
+		}
+		if credential.Enabled() {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		step, ok := totp.Validate(credential.Secret(), req.Code, time.Now(), credential.LastUsedStep())
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The provided code is incorrect"})
+			return
+		}
+
+		var recoveryCodes []string
+		err = db.Transaction(func(tx *gorm.DB) error {
+			enabled := domain.NewTOTPCredential(userID, credential.Secret(), true, step, 0, time.Time{})
+			if _, err := gormrepo.NewTOTPCredentialRepository(tx, c.Request.Context()).Save(enabled); err != nil {
+				return err
+			}
+			var err error
+			recoveryCodes, err = issueRecoveryCodes(gormrepo.NewRecoveryCodeRepository(tx, c.Request.Context()), userID)
+			return err
+		})
+		if err != nil {
+			slog.Error("failed to enable totp", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+			return
+		}
+
+		c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+	}
+}
+
+// 2段階認証の無効化
+// セッションを奪われただけでは無効化できないよう、パスワードと2要素目の両方を求める
+func NewDisableTOTPHandler(db *gorm.DB, hasher *password.Hasher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		totpRepo := gormrepo.NewTOTPCredentialRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		var req DisableTOTPRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		user, err := userRepo.Find(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if !user.PasswordHash().IsEmpty() {
+			ok, _, err := hasher.Verify(req.Password, user.PasswordHash().Value())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+				return
+			}
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Password is incorrect"})
+				return
+			}
+		}
+
+		credential, err := findEnabledTOTP(totpRepo, userID)
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Two-factor authentication is not enabled"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+		if err := verifyLimitedSecondFactor(db, c.Request.Context(), credential, req.SecondFactorRequest); err != nil {
+			if errors.Is(err, errSecondFactorInvalid) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The provided code is incorrect"})
+				return
+			}
+			if errors.Is(err, errSecondFactorLocked) {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect codes. Try again later"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := gormrepo.NewTOTPCredentialRepository(tx, c.Request.Context()).Delete(userID); err != nil {
+				return err
+			}
+			return gormrepo.NewRecoveryCodeRepository(tx, c.Request.Context()).DeleteByUserID(userID)
+		})
+		if err != nil {
+			slog.Error("failed to disable totp", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// リカバリーコードの再発行
+// 未使用のコードも含めて、以前のコードはすべて無効になる
+func NewRegenerateRecoveryCodesHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		totpRepo := gormrepo.NewTOTPCredentialRepository(db, c.Request.Context())
+		recoveryRepo := gormrepo.NewRecoveryCodeRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		var req EnableTOTPRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		credential, err := findEnabledTOTP(totpRepo, userID)
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Two-factor authentication is not enabled"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes"})
+			return
+		}
+		// リカバリーコードで新しいリカバリーコードを作れないよう、認証アプリのコードのみ受け付ける
+		if err := verifyLimitedSecondFactor(db, c.Request.Context(), credential, SecondFactorRequest{Code: req.Code}); err != nil {
+			if errors.Is(err, errSecondFactorInvalid) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The provided code is incorrect"})
+				return
+			}
+			if errors.Is(err, errSecondFactorLocked) {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect codes. Try again later"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes"})
+			return
+		}
+
+		recoveryCodes, err := issueRecoveryCodes(recoveryRepo, userID)
+		if err != nil {
+			slog.Error("failed to regenerate recovery codes", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes"})
+			return
+		}
+
+		c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+	}
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"github.com/iotassss/gizzmd/internal/sso"
+	"github.com/iotassss/gizzmd/internal/token"
+	"gorm.io/gorm"
+)
+
+const oidcLoginStateTTL = 10 * time.Minute
+
+// ログインを開始したブラウザにstateのハッシュを持たせるCookie
+// 他人のコールバックURLを踏ませて攻撃者のアカウントでログインさせる攻撃（ログインCSRF）を防ぐ
+// IdPからのリダイレクト（トップレベルのGET）で送られるよう、SameSite=Laxにする
+const oidcStateCookie = "gizzmd_oidc_state"
+
+var errExternalEmailNotVerified = errors.New("external identity does not have a verified email address")
+
+// 外部IdPのアカウントに対応するUserを返す
+// 紐付け済みでなければ検証済みメールアドレスで既存ユーザーに紐付け、該当がなければ新規作成する
+func resolveOIDCUser(tx *gorm.DB, ctx context.Context, identity sso.Identity) (domain.User, error) {
+	userRepo := gormrepo.NewUserRepository(tx, ctx)
+	identityRepo := gormrepo.NewExternalIdentityRepository(tx, ctx)
+
+	linked, err := identityRepo.FindByIssuerSubject(identity.Issuer, identity.Subject)
+	if err == nil {
+		return userRepo.Find(linked.UserID())
+	}
+	if !errors.Is(err, domain.ErrEntityNotFound) {
+		return domain.User{}, err
+	}
+
+	// 未検証のメールアドレスで紐付けると、他人のアカウントを乗っ取れてしまう
+	if !identity.EmailVerified || identity.Email == "" {
+		return domain.User{}, errExternalEmailNotVerified
+	}
+	email, err := domain.NewEmail(identity.Email)
+	if err != nil {
+		return domain.User{}, errExternalEmailNotVerified
+	}
+
+	user, err := userRepo.FindByEmail(email)
+	switch {
+	case err == nil:
+		if !user.EmailVerified() {
+			verified := domain.NewUser(
+				user.ID(),
+				user.Email(),
+				user.AuthorName(),
+				user.UITheme(),
+				user.PasswordHash(),
+				true,
+				user.ActiveWorkspaceID(),
+			)
+			if user, err = userRepo.Save(verified); err != nil {
+				return domain.User{}, err
+			}
+		}
+	case errors.Is(err, domain.ErrEntityNotFound):
+		authorName, err := oidcAuthorName(identity, email)
+		if err != nil {
+			return domain.User{}, err
+		}
+		created := domain.NewUser(
+			domain.GenerateID(),
+			email,
+			authorName,
+			domain.DefaultUITheme(),
+			domain.NewPasswordHash(""),
+			true,
+			domain.ID{},
+		)
+		if user, err = saveNewUser(tx, ctx, created); err != nil {
+			return domain.User{}, err
+		}
+	default:
+		return domain.User{}, err
+	}
+
+	external := domain.NewExternalIdentity(
+		domain.GenerateID(),
+		user.ID(),
+		identity.Issuer,
+		identity.Subject,
+		email,
+		domain.NewCreatedAtNow(),
+	)
+	if _, err := identityRepo.Save(external); err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// nameクレームがなければメールアドレスのローカル部を著者名とする
+// HTTPSで受けた場合（リバースプロキシ経由を含む）はSecure属性を付ける
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, "/api/oidc", "", secure, true)
+}
+
+func oidcAuthorName(identity sso.Identity, email domain.Email) (domain.AuthorName, error) {
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(email.Value(), "@")
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return domain.NewAuthorName(name)
+}
+
+// OIDCログイン開始
+// IdPの認可エンドポイントへリダイレクトする
+func NewOIDCLoginHandler(db *gorm.DB, provider *sso.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stateRepo := gormrepo.NewOIDCLoginStateRepository(db, c.Request.Context())
+
+		state, stateHash, err := securetoken.Generate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to start login"})
+			return
+		}
+		nonce, _, err := securetoken.Generate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to start login"})
+			return
+		}
+		codeVerifier := sso.GenerateCodeVerifier()
+
+		loginState := domain.NewOIDCLoginState(
+			stateHash,
+			nonce,
+			codeVerifier,
+			domain.NewExpiresAtAfter(oidcLoginStateTTL),
+		)
+		if err := stateRepo.Save(loginState); err != nil {
+			slog.Error("failed to save oidc login state", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to start login"})
+			return
+		}
+
+		setOIDCStateCookie(c, stateHash, int(oidcLoginStateTTL.Seconds()))
+		c.Redirect(http.StatusFound, provider.AuthCodeURL(state, nonce, codeVerifier))
+	}
+}
+
+// OIDCコールバック
+// 認可コードをIdPのトークンと交換し、gizzmdのトークンを発行する
+func NewOIDCCallbackHandler(db *gorm.DB, provider *sso.Provider, tokens *token.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stateRepo := gormrepo.NewOIDCLoginStateRepository(db, c.Request.Context())
+		refreshRepo := gormrepo.NewRefreshTokenRepository(db, c.Request.Context())
+
+		if errCode := c.Query("error"); errCode != "" {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Login was rejected by the identity provider: " + errCode})
+			return
+		}
+		code := c.Query("code")
+		state := c.Query("state")
+		if code == "" || state == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Missing code or state"})
+			return
+		}
+
+		// stateはログインを開始したブラウザのCookieと一致する場合のみ受け付ける
+		cookieStateHash, err := c.Cookie(oidcStateCookie)
+		setOIDCStateCookie(c, "", -1)
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookieStateHash), []byte(securetoken.Hash(state))) != 1 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Login state is invalid or has expired"})
+			return
+		}
+
+		loginState, err := stateRepo.Consume(securetoken.Hash(state))
+		if err != nil {
+			if errors.Is(err, domain.ErrTokenInvalid) {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Login state is invalid or has expired"})
+				return
+			}
+			slog.Error("failed to consume oidc login state", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to complete login"})
+			return
+		}
+
+		identity, err := provider.Exchange(c.Request.Context(), code, loginState.CodeVerifier(), loginState.Nonce())
+		if err != nil {
+			slog.Warn("failed to exchange oidc authorization code", slog.Any("error", err))
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Failed to authenticate with the identity provider"})
+			return
+		}
+
+		var user domain.User
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			user, err = resolveOIDCUser(tx, c.Request.Context(), identity)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, errExternalEmailNotVerified) {
+				c.JSON(http.StatusForbidden, ErrorResponse{Message: "The identity provider did not return a verified email address"})
+				return
+			}
+			slog.Error("failed to resolve oidc user", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to complete login"})
+			return
+		}
+
+		// メールアドレスでアカウントを結び付けるため、IdPのアカウントだけで2段階認証を回避できないようにする
+		// 2段階認証が有効な場合は、パスワードでのログインと同じくチャレンジを返して /login/mfa で続きを受け付ける
+		if _, err := findEnabledTOTP(gormrepo.NewTOTPCredentialRepository(db, c.Request.Context()), user.ID()); err == nil {
+			challenge, err := issueMFAChallenge(gormrepo.NewMFAChallengeRepository(db, c.Request.Context()), user.ID())
+			if err != nil {
+				slog.Error("failed to issue mfa challenge", slog.Any("error", err))
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to complete login"})
+				return
+			}
+			c.JSON(http.StatusOK, challenge)
+			return
+		} else if !errors.Is(err, domain.ErrEntityNotFound) {
+			slog.Error("failed to find totp credential", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to complete login"})
+			return
+		}
+
+		resp, err := issueLoginTokens(tokens, refreshRepo, user)
+		if err != nil {
+			slog.Error("failed to issue tokens", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/mailer"
+	"github.com/iotassss/gizzmd/internal/password"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"gorm.io/gorm"
+)
+
+const passwordResetTTL = 1 * time.Hour
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func withPasswordHash(user domain.User, hash string) domain.User {
+	return domain.NewUser(
+		user.ID(),
+		user.Email(),
+		user.AuthorName(),
+		user.UITheme(),
+		domain.NewPasswordHash(hash),
+		user.EmailVerified(),
+		user.ActiveWorkspaceID(),
+	)
+}
+
+// パスワード変更
+func NewChangePasswordHandler(db *gorm.DB, hasher *password.Hasher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+
+		userIDStr, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		userID, err := domain.NewID(userIDStr.(string))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		var req ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		newPassword, err := domain.NewPassword(req.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := userRepo.Find(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if user.PasswordHash().IsEmpty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+		ok, _, err := hasher.Verify(req.CurrentPassword, user.PasswordHash().Value())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+
+		hash, err := hasher.Hash(newPassword.Value())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+			return
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if _, err := gormrepo.NewUserRepository(tx, c.Request.Context()).Save(withPasswordHash(user, hash)); err != nil {
+				return err
+			}
+			// 他の端末のセッションは、アクセストークンの期限切れ後に再ログインを必要とする
+			return gormrepo.NewRefreshTokenRepository(tx, c.Request.Context()).RevokeAllForUser(user.ID())
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// パスワード再設定メール送信
+// アカウントの有無を推測されないよう、結果に関わらず同じレスポンスを返す
+func NewForgotPasswordHandler(db *gorm.DB, m mailer.Mailer, appBaseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		tokenRepo := gormrepo.NewPasswordResetTokenRepository(db, c.Request.Context())
+
+		var req ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+		resp := MessageResponse{Message: "If the account exists, a password reset email has been sent"}
+
+		email, err := domain.NewEmail(req.Email)
+		if err != nil {
+			c.JSON(http.StatusAccepted, resp)
+			return
+		}
+		user, err := userRepo.FindByEmail(email)
+		if err != nil {
+			if !errors.Is(err, domain.ErrEntityNotFound) {
+				slog.Error("failed to find user", slog.Any("error", err))
+			}
+			c.JSON(http.StatusAccepted, resp)
+			return
+		}
+
+		// 古いトークンは無効化し、最新のメールのリンクのみ有効にする
+		if err := tokenRepo.DeleteByUserID(user.ID()); err != nil {
+			slog.Error("failed to delete password reset tokens", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to process password reset request"})
+			return
+		}
+		plain, hash, err := securetoken.Generate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to process password reset request"})
+			return
+		}
+		token := domain.NewPasswordResetToken(
+			domain.GenerateID(),
+			user.ID(),
+			hash,
+			domain.NewExpiresAtAfter(passwordResetTTL),
+		)
+		if _, err := tokenRepo.Save(token); err != nil {
+			slog.Error("failed to save password reset token", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to process password reset request"})
+			return
+		}
+
+		link := fmt.Sprintf("%s/reset-password?token=%s", appBaseURL, url.QueryEscape(plain))
+		err = m.Send(c.Request.Context(), mailer.Message{
+			To:      user.Email().Value(),
+			Subject: "[GizzMD] パスワードの再設定",
+			Body: fmt.Sprintf(
+				"パスワード再設定のリクエストを受け付けました。\n以下のリンクから新しいパスワードを設定してください（%d分間有効）。\n\n%s\n\nお心当たりがない場合は、このメールを破棄してください。\n",
+				int(passwordResetTTL.Minutes()), link,
+			),
+		})
+		if err != nil {
+			slog.Error("failed to send password reset mail", slog.Any("error", err), slog.String("user_id", user.ID().String()))
+		}
+
+		c.JSON(http.StatusAccepted, resp)
+	}
+}
+
+// パスワード再設定
+func NewResetPasswordHandler(db *gorm.DB, hasher *password.Hasher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+		newPassword, err := domain.NewPassword(req.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+			return
+		}
+		hash, err := hasher.Hash(newPassword.Value())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to reset password"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			userRepo := gormrepo.NewUserRepository(tx, c.Request.Context())
+			tokenRepo := gormrepo.NewPasswordResetTokenRepository(tx, c.Request.Context())
+
+			userID, err := tokenRepo.Consume(securetoken.Hash(req.Token))
+			if err != nil {
+				return err
+			}
+			user, err := userRepo.Find(userID)
+			if err != nil {
+				return err
+			}
+			// 再設定メールを受け取れたことで、メールアドレスの所有も確認できている
+			updated := domain.NewUser(
+				user.ID(),
+				user.Email(),
+				user.AuthorName(),
+				user.UITheme(),
+				domain.NewPasswordHash(hash),
+				true,
+				user.ActiveWorkspaceID(),
+			)
+			if _, err := userRepo.Save(updated); err != nil {
+				return err
+			}
+			if err := gormrepo.NewRefreshTokenRepository(tx, c.Request.Context()).RevokeAllForUser(user.ID()); err != nil {
+				return err
+			}
+			return tokenRepo.DeleteByUserID(user.ID())
+		})
+		if err != nil {
+			if errors.Is(err, domain.ErrTokenInvalid) || errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Reset token is invalid or has expired"})
+				return
+			}
+			slog.Error("failed to reset password", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to reset password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, MessageResponse{Message: "Password has been reset"})
+	}
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"gorm.io/gorm"
+)
+
+// JWTと区別できるよう、パーソナルアクセストークンには接頭辞を付ける
+const PersonalAccessTokenPrefix = "gzm_pat_"
+
+const maxPersonalAccessTokenDays = 365
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days,omitempty"` // 未指定の場合は無期限
+}
+
+type PersonalAccessTokenResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+type CreatePersonalAccessTokenResponse struct {
+	PersonalAccessTokenResponse
+	Token string `json:"token"` // 作成時にのみ返す
+}
+
+type ListPersonalAccessTokensResponse struct {
+	Tokens []PersonalAccessTokenResponse `json:"tokens"`
+}
+
+func toPersonalAccessTokenResponse(token domain.PersonalAccessToken) PersonalAccessTokenResponse {
+	resp := PersonalAccessTokenResponse{
+		ID:        token.ID().String(),
+		Name:      token.Name().String(),
+		Scopes:    token.Scopes().Values(),
+		CreatedAt: token.CreatedAt().String(),
+	}
+	if !token.ExpiresAt().IsZero() {
+		expiresAt := token.ExpiresAt().String()
+		resp.ExpiresAt = &expiresAt
+	}
+	if !token.LastUsedAt().IsZero() {
+		lastUsedAt := token.LastUsedAt().Format(time.RFC3339)
+		resp.LastUsedAt = &lastUsedAt
+	}
+	return resp
+}
+
+// パーソナルアクセストークン一覧
+func NewListPersonalAccessTokensHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenRepo := gormrepo.NewPersonalAccessTokenRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		tokens, err := tokenRepo.FindByUserID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tokens"})
+			return
+		}
+
+		resp := ListPersonalAccessTokensResponse{Tokens: make([]PersonalAccessTokenResponse, len(tokens))}
+		for i, token := range tokens {
+			resp.Tokens[i] = toPersonalAccessTokenResponse(token)
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// パーソナルアクセストークン作成
+func NewCreatePersonalAccessTokenHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenRepo := gormrepo.NewPersonalAccessTokenRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		var req CreatePersonalAccessTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		name, err := domain.NewTokenName(req.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		scopes, err := domain.NewScopes(req.Scopes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var expiresAt domain.ExpiresAt
+		if req.ExpiresInDays != nil {
+			if *req.ExpiresInDays < 1 || *req.ExpiresInDays > maxPersonalAccessTokenDays {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 1 and 365"})
+				return
+			}
+			expiresAt = domain.NewExpiresAtAfter(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		}
+
+		plain, hash, err := securetoken.GenerateWithPrefix(PersonalAccessTokenPrefix)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+			return
+		}
+		token := domain.NewPersonalAccessToken(
+			domain.GenerateID(),
+			userID,
+			name,
+			hash,
+			scopes,
+			expiresAt,
+			time.Time{},
+			domain.NewCreatedAtNow(),
+		)
+		saved, err := tokenRepo.Save(token)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, CreatePersonalAccessTokenResponse{
+			PersonalAccessTokenResponse: toPersonalAccessTokenResponse(saved),
+			Token:                       plain,
+		})
+	}
+}
+
+// パーソナルアクセストークン失効
+func NewRevokePersonalAccessTokenHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenRepo := gormrepo.NewPersonalAccessTokenRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		tokenID, err := domain.NewID(c.Param("token_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+			return
+		}
+
+		if err := tokenRepo.Revoke(tokenID, userID); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
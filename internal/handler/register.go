@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/mailer"
+	"github.com/iotassss/gizzmd/internal/password"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"gorm.io/gorm"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+type RegisterRequest struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	AuthorName string `json:"author_name"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// 確認メールのトークンを発行する。返り値は平文のトークン
+func issueEmailVerificationToken(tokenRepo *gormrepo.EmailVerificationTokenRepository, userID domain.ID) (string, error) {
+	plain, hash, err := securetoken.Generate()
+	if err != nil {
+		return "", err
+	}
+	token := domain.NewEmailVerificationToken(
+		domain.GenerateID(),
+		userID,
+		hash,
+		domain.NewExpiresAtAfter(emailVerificationTTL),
+	)
+	if _, err := tokenRepo.Save(token); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+func sendVerificationMail(ctx context.Context, m mailer.Mailer, appBaseURL string, email domain.Email, token string) error {
+	link := fmt.Sprintf("%s/verify-email?token=%s", appBaseURL, url.QueryEscape(token))
+	return m.Send(ctx, mailer.Message{
+		To:      email.Value(),
+		Subject: "[GizzMD] メールアドレスの確認",
+		Body: fmt.Sprintf(
+			"GizzMDへのご登録ありがとうございます。\n以下のリンクからメールアドレスを確認してください（%d時間有効）。\n\n%s\n",
+			int(emailVerificationTTL.Hours()), link,
+		),
+	})
+}
+
+// 登録済みのメールアドレスで登録しようとした場合に、その持ち主に知らせる
+func sendAlreadyRegisteredMail(ctx context.Context, m mailer.Mailer, appBaseURL string, email domain.Email) error {
+	return m.Send(ctx, mailer.Message{
+		To:      email.Value(),
+		Subject: "[GizzMD] アカウント登録のお知らせ",
+		Body: fmt.Sprintf(
+			"このメールアドレスでアカウント登録が試みられましたが、既に登録済みです。\n以下からログインしてください。パスワードを忘れた場合は、ログイン画面から再設定できます。\n\n%s/login\n\nお心当たりがない場合は、このメールを破棄してください。\n",
+			appBaseURL,
+		),
+	})
+}
+
+// ユーザー登録
+// 登録済みのメールアドレスかどうかを知られないよう、登録済みの場合も同じレスポンスを返し、持ち主にはお知らせのメールを送る
+func NewRegisterHandler(db *gorm.DB, hasher *password.Hasher, m mailer.Mailer, appBaseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+		email, err := domain.NewEmail(req.Email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+			return
+		}
+		authorName, err := domain.NewAuthorName(req.AuthorName)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+			return
+		}
+		rawPassword, err := domain.NewPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+			return
+		}
+		hash, err := hasher.Hash(rawPassword.Value())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to register user"})
+			return
+		}
+
+		resp := MessageResponse{Message: "Please check your email to complete your registration"}
+
+		var saved domain.User
+		var token string
+		err = db.Transaction(func(tx *gorm.DB) error {
+			userRepo := gormrepo.NewUserRepository(tx, c.Request.Context())
+			tokenRepo := gormrepo.NewEmailVerificationTokenRepository(tx, c.Request.Context())
+
+			if existing, err := userRepo.FindByEmail(email); err == nil {
+				saved = existing
+				return domain.ErrEntityAlreadyExists
+			} else if !errors.Is(err, domain.ErrEntityNotFound) {
+				return err
+			}
+
+			user := domain.NewUser(
+				domain.GenerateID(),
+				email,
+				authorName,
+				domain.DefaultUITheme(),
+				domain.NewPasswordHash(hash),
+				false,
+				domain.ID{},
+			)
+			if saved, err = saveNewUser(tx, c.Request.Context(), user); err != nil {
+				return err
+			}
+			token, err = issueEmailVerificationToken(tokenRepo, saved.ID())
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityAlreadyExists) {
+				// 同時に登録されて一意制約に違反した場合は、確定した登録済みのユーザーを読み直す
+				if saved.ID().IsNil() {
+					if saved, err = gormrepo.NewUserRepository(db, c.Request.Context()).FindByEmail(email); err != nil {
+						slog.Error("failed to find already registered user", slog.Any("error", err))
+						c.JSON(http.StatusAccepted, resp)
+						return
+					}
+				}
+				if err := sendAlreadyRegisteredMail(c.Request.Context(), m, appBaseURL, saved.Email()); err != nil {
+					slog.Error("failed to send already registered mail", slog.Any("error", err), slog.String("user_id", saved.ID().String()))
+				}
+				c.JSON(http.StatusAccepted, resp)
+				return
+			}
+			slog.Error("failed to register user", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to register user"})
+			return
+		}
+
+		// 送信に失敗しても登録自体は完了しているため、再送APIで復旧できる
+		if err := sendVerificationMail(c.Request.Context(), m, appBaseURL, saved.Email(), token); err != nil {
+			slog.Error("failed to send verification mail", slog.Any("error", err), slog.String("user_id", saved.ID().String()))
+		}
+
+		c.JSON(http.StatusAccepted, resp)
+	}
+}
+
+// メールアドレス確認
+func NewVerifyEmailHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			userRepo := gormrepo.NewUserRepository(tx, c.Request.Context())
+			tokenRepo := gormrepo.NewEmailVerificationTokenRepository(tx, c.Request.Context())
+
+			userID, err := tokenRepo.Consume(securetoken.Hash(req.Token))
+			if err != nil {
+				return err
+			}
+			user, err := userRepo.Find(userID)
+			if err != nil {
+				return err
+			}
+			verified := domain.NewUser(
+				user.ID(),
+				user.Email(),
+				user.AuthorName(),
+				user.UITheme(),
+				user.PasswordHash(),
+				true,
+				user.ActiveWorkspaceID(),
+			)
+			if _, err := userRepo.Save(verified); err != nil {
+				return err
+			}
+			return tokenRepo.DeleteByUserID(user.ID())
+		})
+		if err != nil {
+			if errors.Is(err, domain.ErrTokenInvalid) || errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Verification token is invalid or has expired"})
+				return
+			}
+			slog.Error("failed to verify email", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to verify email"})
+			return
+		}
+
+		c.JSON(http.StatusOK, MessageResponse{Message: "Email address verified"})
+	}
+}
+
+// 確認メール再送
+// アカウントの有無を推測されないよう、結果に関わらず同じレスポンスを返す
+func NewResendVerificationHandler(db *gorm.DB, m mailer.Mailer, appBaseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		tokenRepo := gormrepo.NewEmailVerificationTokenRepository(db, c.Request.Context())
+
+		var req ResendVerificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+		resp := MessageResponse{Message: "If the account exists and is not yet verified, a verification email has been sent"}
+
+		email, err := domain.NewEmail(req.Email)
+		if err != nil {
+			c.JSON(http.StatusAccepted, resp)
+			return
+		}
+		user, err := userRepo.FindByEmail(email)
+		if err != nil || user.EmailVerified() {
+			c.JSON(http.StatusAccepted, resp)
+			return
+		}
+
+		// 古いトークンは無効化し、最新のメールのリンクのみ有効にする
+		if err := tokenRepo.DeleteByUserID(user.ID()); err != nil {
+			slog.Error("failed to delete verification tokens", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to resend verification email"})
+			return
+		}
+		token, err := issueEmailVerificationToken(tokenRepo, user.ID())
+		if err != nil {
+			slog.Error("failed to issue verification token", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to resend verification email"})
+			return
+		}
+		if err := sendVerificationMail(c.Request.Context(), m, appBaseURL, user.Email(), token); err != nil {
+			slog.Error("failed to send verification mail", slog.Any("error", err), slog.String("user_id", user.ID().String()))
+		}
+
+		c.JSON(http.StatusAccepted, resp)
+	}
+}
@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
+	"github.com/iotassss/gizzmd/internal/password"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"gorm.io/gorm"
+)
+
+// パスワード付きの共有リンクを閲覧する際に指定するヘッダー
+// URLに含めるとアクセスログなどに残るため、クエリパラメータでは受け付けない
+const SharePasswordHeader = "X-Share-Password"
+
+const maxShareLinkDays = 365
+
+// 共有リンクのパスワードをmaxSharePasswordAttempts回続けて間違えると、sharePasswordLockoutの間は受け付けない
+// 総当たりを防ぎ、パスワードの検証（argon2id）で負荷をかけられないよう、検証の前に判定する
+const (
+	maxSharePasswordAttempts = 10
+	sharePasswordLockout     = 15 * time.Minute
+)
+
+type CreateShareLinkRequest struct {
+	ExpiresInDays *int   `json:"expires_in_days,omitempty"` // 未指定の場合は無期限
+	Password      string `json:"password,omitempty"`        // 未指定の場合はパスワードなし
+}
+
+type ShareLinkResponse struct {
+	ID                string  `json:"id"`
+	PasswordProtected bool    `json:"password_protected"`
+	ExpiresAt         *string `json:"expires_at"`
+	AccessCount       int     `json:"access_count"`
+	LastAccessedAt    *string `json:"last_accessed_at"`
+	CreatedBy         string  `json:"created_by"`
+	CreatedAt         string  `json:"created_at"`
+}
+
+type CreateShareLinkResponse struct {
+	ShareLinkResponse
+	Token string `json:"token"` // 作成時にのみ返す
+	URL   string `json:"url"`
+}
+
+type ListShareLinksResponse struct {
+	ShareLinks []ShareLinkResponse `json:"share_links"`
+}
+
+// 共有リンクで閲覧したドキュメント
+// formatに応じてcontent（Markdown）かhtmlのどちらか一方を返す
+type SharedDocResponse struct {
+	Title    string   `json:"title"`
+	Tags     []string `json:"tags"`
+	Content  *string  `json:"content,omitempty"`
+	HTML     *string  `json:"html,omitempty"`
+	EditedAt string   `json:"edited_at"`
+}
+
+func toShareLinkResponse(link domain.ShareLink) ShareLinkResponse {
+	resp := ShareLinkResponse{
+		ID:                link.ID().String(),
+		PasswordProtected: link.IsPasswordProtected(),
+		AccessCount:       link.AccessCount(),
+		CreatedBy:         link.CreatedBy().String(),
+		CreatedAt:         link.CreatedAt().String(),
+	}
+	if !link.ExpiresAt().IsZero() {
+		expiresAt := link.ExpiresAt().String()
+		resp.ExpiresAt = &expiresAt
+	}
+	if !link.LastAccessedAt().IsZero() {
+		lastAccessedAt := link.LastAccessedAt().Format(time.RFC3339)
+		resp.LastAccessedAt = &lastAccessedAt
+	}
+	return resp
+}
+
+// 共有リンク一覧
+func NewListShareLinksHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		linkRepo := gormrepo.NewShareLinkRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionManagePermissions)
+		if !ok {
+			return
+		}
+
+		links, err := linkRepo.FindByDocID(doc.ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve share links"})
+			return
+		}
+
+		resp := ListShareLinksResponse{ShareLinks: make([]ShareLinkResponse, len(links))}
+		for i, link := range links {
+			resp.ShareLinks[i] = toShareLinkResponse(link)
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// 共有リンク作成
+// アカウントを持たない相手にも公開されるため、ownerのみ作成できる
+func NewCreateShareLinkHandler(db *gorm.DB, hasher *password.Hasher, appBaseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		linkRepo := gormrepo.NewShareLinkRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		var req CreateShareLinkRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+				return
+			}
+		}
+		var expiresAt domain.ExpiresAt
+		if req.ExpiresInDays != nil {
+			if *req.ExpiresInDays < 1 || *req.ExpiresInDays > maxShareLinkDays {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 1 and 365"})
+				return
+			}
+			expiresAt = domain.NewExpiresAtAfter(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		}
+		var passwordHash domain.PasswordHash
+		if req.Password != "" {
+			sharePassword, err := domain.NewPassword(req.Password)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			hash, err := hasher.Hash(sharePassword.Value())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+				return
+			}
+			passwordHash = domain.NewPasswordHash(hash)
+		}
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionManagePermissions)
+		if !ok {
+			return
+		}
+		createdBy, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		plain, hash, err := securetoken.Generate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+			return
+		}
+		saved, err := linkRepo.Save(domain.NewShareLink(
+			domain.GenerateID(),
+			doc.ID(),
+			hash,
+			passwordHash,
+			expiresAt,
+			createdBy,
+			0,
+			time.Time{},
+			0,
+			time.Time{},
+			domain.NewCreatedAtNow(),
+		))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, CreateShareLinkResponse{
+			ShareLinkResponse: toShareLinkResponse(saved),
+			Token:             plain,
+			URL:               appBaseURL + "/shared/" + plain,
+		})
+	}
+}
+
+// 共有リンク失効
+func NewRevokeShareLinkHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		linkRepo := gormrepo.NewShareLinkRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		linkID, err := domain.NewID(c.Param("link_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+			return
+		}
+
+		doc, ok := findAuthorizedDoc(c, docRepo, policy, authz.ActionManagePermissions)
+		if !ok {
+			return
+		}
+
+		if err := linkRepo.Revoke(linkID, doc.ID()); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// 共有リンクでのドキュメント閲覧（認証不要）
+// format=raw の場合はMarkdownを、それ以外はHTMLに変換して返す
+func NewGetSharedDocHandler(db *gorm.DB, hasher *password.Hasher, renderCache *markdown.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		linkRepo := gormrepo.NewShareLinkRepository(db, c.Request.Context())
+
+		format := c.DefaultQuery("format", "html")
+		if format != "html" && format != "raw" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'html' or 'raw'"})
+			return
+		}
+
+		// 失効・期限切れ・存在しないリンクは区別せずに返す
+		link, err := linkRepo.FindByHash(securetoken.Hash(c.Param("token")))
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+			return
+		}
+		if link.ExpiresAt().IsExpired() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+
+		if link.IsPasswordProtected() {
+			sharePassword := c.GetHeader(SharePasswordHeader)
+			if sharePassword == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is required", "password_required": true})
+				return
+			}
+			if link.FailedAttempts() >= maxSharePasswordAttempts {
+				if retryAfter := time.Until(link.LastFailedAt().Add(sharePasswordLockout)); retryAfter > 0 {
+					c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+					c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect passwords. Try again later"})
+					return
+				}
+			}
+			ok, _, err := hasher.Verify(sharePassword, link.PasswordHash().Value())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+				return
+			}
+			if !ok {
+				attempts, err := linkRepo.RecordFailure(link.ID(), sharePasswordLockout)
+				if err != nil {
+					slog.Error("failed to record share link password failure", slog.Any("error", err), slog.String("share_link_id", link.ID().String()))
+				}
+				if attempts >= maxSharePasswordAttempts {
+					slog.Warn("share link password attempts exceeded", slog.String("share_link_id", link.ID().String()))
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect", "password_required": true})
+				return
+			}
+			if link.FailedAttempts() > 0 {
+				if err := linkRepo.ResetFailures(link.ID()); err != nil {
+					slog.Error("failed to reset share link password failures", slog.Any("error", err), slog.String("share_link_id", link.ID().String()))
+				}
+			}
+		}
+
+		doc, err := docRepo.Find(link.DocID())
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+			return
+		}
+
+		resp := SharedDocResponse{
+			Title:    doc.Title().String(),
+			Tags:     doc.Tags().Values(),
+			EditedAt: doc.EditedAt().String(),
+		}
+		if format == "raw" {
+			content := doc.Content().String()
+			resp.Content = &content
+		} else {
+			html, err := renderCache.Render(doc.Content().Value())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render document"})
+				return
+			}
+			resp.HTML = &html
+		}
+
+		if err := linkRepo.RecordAccess(link.ID()); err != nil {
+			slog.Error("failed to record share link access", slog.Any("error", err), slog.String("share_link_id", link.ID().String()))
+		}
+
+		// 共有リンクの閲覧者は第三者のため、検索エンジンや共有キャッシュに残さない
+		c.Header("Cache-Control", "no-store")
+		c.Header("X-Robots-Tag", "noindex")
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+// リクエストのタグ
+// JSON配列のほか、以前の形式のカンマ区切り文字列も受け付ける
+type TagList struct {
+	tags domain.Tags
+	// 空文字を指定した場合はtrue。以前の形式では空文字を「変更しない」として扱っていた
+	emptyString bool
+}
+
+func (t *TagList) UnmarshalJSON(data []byte) error {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		tags, err := domain.NewTagList(values)
+		if err != nil {
+			return err
+		}
+		t.tags = tags
+		return nil
+	}
+
+	var csv string
+	if err := json.Unmarshal(data, &csv); err != nil {
+		return errors.New("tags must be an array of strings")
+	}
+	tags, err := domain.NewTags(csv)
+	if err != nil {
+		return err
+	}
+	t.tags = tags
+	t.emptyString = csv == ""
+	return nil
+}
+
+func (t TagList) Tags() domain.Tags { return t.tags }
+
+const (
+	defaultTagListLimit = 50
+	maxTagListLimit     = 200
+)
+
+type TagCountResponse struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"` // タグを付けたドキュメントの数（ゴミ箱を除く）
+}
+
+type ListTagsResponse struct {
+	Tags []TagCountResponse `json:"tags"`
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type MergeTagsRequest struct {
+	Sources []string `json:"sources" binding:"required"`
+	Target  string   `json:"target" binding:"required"`
+}
+
+type UpdateTagsResponse struct {
+	Name             string `json:"name"`
+	UpdatedDocuments int    `json:"updated_documents"`
+}
+
+// アクティブなワークスペースについて、認証ユーザーのメンバー情報を取得する
+// manageがtrueの場合はタグを一括で変更できる権限も確認する。取得できない場合はエラーレスポンスを書き込み、falseを返す
+func findTagWorkspaceMember(c *gin.Context, db *gorm.DB, manage bool) (domain.WorkspaceMember, bool) {
+	userID, err := domain.NewID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return domain.WorkspaceMember{}, false
+	}
+	workspaceID, err := activeWorkspaceID(c, db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspace"})
+		return domain.WorkspaceMember{}, false
+	}
+	member, err := gormrepo.NewWorkspaceMemberRepository(db, c.Request.Context()).Find(workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of the active workspace"})
+			return domain.WorkspaceMember{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspace"})
+		return domain.WorkspaceMember{}, false
+	}
+	if manage && !member.Role().CanManageTags() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+		return domain.WorkspaceMember{}, false
+	}
+	return member, true
+}
+
+// タグの一括変更のエラーレスポンスを書き込む
+func respondTagUpdateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrEntityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+	case errors.Is(err, domain.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "A document was modified during the update. Please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+	}
+}
+
+// タグの一覧
+// アクティブなワークスペースで使われているタグを、ドキュメント数の多い順に返す。prefixで前方一致の絞り込みができる
+func NewListTagsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		member, ok := findTagWorkspaceMember(c, db, false)
+		if !ok {
+			return
+		}
+
+		limit := defaultTagListLimit
+		if limitStr := c.Query("limit"); limitStr != "" {
+			if limitInt, err := strconv.Atoi(limitStr); err == nil && limitInt > 0 && limitInt <= maxTagListLimit {
+				limit = limitInt
+			}
+		}
+
+		counts, err := gormrepo.NewTagRepository(db, c.Request.Context()).
+			FindCounts(member.WorkspaceID(), strings.TrimSpace(c.Query("prefix")), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags"})
+			return
+		}
+
+		tags := make([]TagCountResponse, len(counts))
+		for i, count := range counts {
+			tags[i] = TagCountResponse{Name: count.Name(), Count: count.Count()}
+		}
+		c.JSON(http.StatusOK, ListTagsResponse{Tags: tags})
+	}
+}
+
+// タグの名前変更
+// 変更後の名前のタグがすでにある場合は、そのタグに統合する
+func NewRenameTagHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		member, ok := findTagWorkspaceMember(c, db, true)
+		if !ok {
+			return
+		}
+
+		var req RenameTagRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if err := domain.ValidateTagName(name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if name == c.Param("name") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New name must be different from the current name"})
+			return
+		}
+
+		docs, err := gormrepo.NewTagRepository(db, c.Request.Context()).
+			Merge(member.WorkspaceID(), []string{c.Param("name")}, name, member.UserID())
+		if err != nil {
+			respondTagUpdateError(c, err)
+			return
+		}
+		for _, doc := range docs {
+			indexDoc(index, doc)
+		}
+		c.JSON(http.StatusOK, UpdateTagsResponse{Name: name, UpdatedDocuments: len(docs)})
+	}
+}
+
+// タグの統合
+// sourcesのタグをtargetに置き換える。targetはsourcesに含まれていてもよい
+func NewMergeTagsHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		member, ok := findTagWorkspaceMember(c, db, true)
+		if !ok {
+			return
+		}
+
+		var req MergeTagsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.Sources) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sources must contain at least one tag"})
+			return
+		}
+		target := strings.TrimSpace(req.Target)
+		if err := domain.ValidateTagName(target); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sources := make([]string, len(req.Sources))
+		for i, source := range req.Sources {
+			sources[i] = strings.TrimSpace(source)
+			if err := domain.ValidateTagName(sources[i]); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		docs, err := gormrepo.NewTagRepository(db, c.Request.Context()).
+			Merge(member.WorkspaceID(), sources, target, member.UserID())
+		if err != nil {
+			respondTagUpdateError(c, err)
+			return
+		}
+		for _, doc := range docs {
+			indexDoc(index, doc)
+		}
+		c.JSON(http.StatusOK, UpdateTagsResponse{Name: target, UpdatedDocuments: len(docs)})
+	}
+}
+
+// タグの削除
+// ワークスペース内のすべてのドキュメントからタグを外す
+func NewDeleteTagHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		member, ok := findTagWorkspaceMember(c, db, true)
+		if !ok {
+			return
+		}
+
+		docs, err := gormrepo.NewTagRepository(db, c.Request.Context()).
+			Delete(member.WorkspaceID(), c.Param("name"), member.UserID())
+		if err != nil {
+			respondTagUpdateError(c, err)
+			return
+		}
+		for _, doc := range docs {
+			indexDoc(index, doc)
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
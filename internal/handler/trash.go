@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+type TrashedDocSummary struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Preview   string   `json:"preview"`
+	Tags      []string `json:"tags"`
+	AuthorID  string   `json:"author_id"`
+	DeletedAt string   `json:"deleted_at"`
+	PurgeAt   *string  `json:"purge_at"` // この日時を過ぎると完全に削除される。自動削除しない設定の場合はnull
+}
+
+type ListTrashResponse struct {
+	Documents  []TrashedDocSummary `json:"documents"`
+	Pagination PaginationInfo      `json:"pagination"`
+}
+
+// ゴミ箱のドキュメントを取得し、認証ユーザーが復元・完全削除できるか判定する
+// 実行できない場合はエラーレスポンスを書き込み、falseを返す
+func findAuthorizedTrashedDoc(c *gin.Context, docRepo *gormrepo.DocRepository, policy *authz.DocPolicy) (domain.TrashedDoc, bool) {
+	userID, err := domain.NewID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return domain.TrashedDoc{}, false
+	}
+	docID, err := domain.NewID(c.Param("doc_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return domain.TrashedDoc{}, false
+	}
+
+	trashed, err := docRepo.FindTrashed(docID)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found in trash"})
+			return domain.TrashedDoc{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+		return domain.TrashedDoc{}, false
+	}
+
+	// ゴミ箱の操作は削除と同じ権限で許可する
+	if !authorizeDoc(c, policy, userID, trashed.Doc(), authz.ActionDelete) {
+		return domain.TrashedDoc{}, false
+	}
+	return trashed, true
+}
+
+// ゴミ箱の一覧
+// アクティブなワークスペースのうち、認証ユーザーが復元できるドキュメントを削除日時の新しい順に返す
+func NewListTrashHandler(db *gorm.DB, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+
+		viewerID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		workspaceID, err := activeWorkspaceID(c, db, viewerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trash"})
+			return
+		}
+
+		page := domain.DefaultPage()
+		if pageStr := c.Query("page"); pageStr != "" {
+			if pageInt, err := strconv.Atoi(pageStr); err == nil && pageInt > 0 {
+				page, _ = domain.NewPage(pageInt)
+			}
+		}
+
+		limit, _ := domain.NewLimit(20)
+		if limitStr := c.Query("limit"); limitStr != "" {
+			if limitInt, err := strconv.Atoi(limitStr); err == nil && limitInt > 0 && limitInt <= 100 {
+				limit, _ = domain.NewLimit(limitInt)
+			}
+		}
+
+		trashed, total, err := docRepo.FindTrash(viewerID, workspaceID, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trash"})
+			return
+		}
+
+		documents := make([]TrashedDocSummary, len(trashed))
+		for i, t := range trashed {
+			documents[i] = TrashedDocSummary{
+				ID:        t.Doc().ID().String(),
+				Title:     t.Doc().Title().String(),
+				Preview:   t.Doc().Snippet().String(),
+				Tags:      t.Doc().Tags().Values(),
+				AuthorID:  t.Doc().AuthorId().String(),
+				DeletedAt: t.DeletedAt().Format(time.RFC3339),
+			}
+			if retention > 0 {
+				purgeAt := t.DeletedAt().Add(retention).Format(time.RFC3339)
+				documents[i].PurgeAt = &purgeAt
+			}
+		}
+
+		totalPages := (total + limit.Value() - 1) / limit.Value()
+		c.JSON(http.StatusOK, ListTrashResponse{
+			Documents: documents,
+			Pagination: PaginationInfo{
+				Page:       page.Value(),
+				Limit:      limit.Value(),
+				Total:      total,
+				TotalPages: totalPages,
+				HasNext:    page.Value() < totalPages,
+				HasPrev:    page.Value() > 1,
+			},
+		})
+	}
+}
+
+// ゴミ箱からの復元
+func NewRestoreTrashedDocHandler(db *gorm.DB, index domain.SearchIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		trashed, ok := findAuthorizedTrashedDoc(c, docRepo, policy)
+		if !ok {
+			return
+		}
+
+		if err := docRepo.Restore(trashed.Doc().ID()); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found in trash"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore document"})
+			return
+		}
+		// 復元時に親や並び順が変わるため、復元後のドキュメントを返す
+		restored, err := docRepo.Find(trashed.Doc().ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+			return
+		}
+		indexDoc(index, restored)
+
+		c.Header("ETag", docETag(restored))
+		c.JSON(http.StatusOK, toGetDocResponse(restored))
+	}
+}
+
+// ゴミ箱からの完全削除
+// リビジョン・共有設定・共有リンクもあわせて削除し、元に戻せない
+func NewPurgeTrashedDocHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docRepo := gormrepo.NewDocRepository(db, c.Request.Context())
+		policy := newDocPolicy(db, c.Request.Context())
+
+		trashed, ok := findAuthorizedTrashedDoc(c, docRepo, policy)
+		if !ok {
+			return
+		}
+
+		if err := docRepo.Purge(trashed.Doc().ID()); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document not found in trash"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
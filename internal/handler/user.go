@@ -96,6 +96,9 @@ func NewUpdateUserHandler(db *gorm.DB) gin.HandlerFunc {
 				user.Email(),
 				authorName,
 				user.UITheme(),
+				user.PasswordHash(),
+				user.EmailVerified(),
+				user.ActiveWorkspaceID(),
 			)
 		}
 
@@ -110,6 +113,9 @@ func NewUpdateUserHandler(db *gorm.DB) gin.HandlerFunc {
 				updatedUser.Email(),
 				updatedUser.AuthorName(),
 				uiTheme,
+				updatedUser.PasswordHash(),
+				updatedUser.EmailVerified(),
+				updatedUser.ActiveWorkspaceID(),
 			)
 		}
 
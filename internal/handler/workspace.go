@@ -0,0 +1,491 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/token"
+	"gorm.io/gorm"
+)
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type AddWorkspaceMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type WorkspaceResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Personal  bool   `json:"personal"`
+	Role      string `json:"role"`
+	Active    bool   `json:"active"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ListWorkspacesResponse struct {
+	Workspaces []WorkspaceResponse `json:"workspaces"`
+}
+
+type WorkspaceMemberResponse struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	JoinedAt string `json:"joined_at"`
+}
+
+type ListWorkspaceMembersResponse struct {
+	Members []WorkspaceMemberResponse `json:"members"`
+}
+
+type SwitchWorkspaceResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	User        User   `json:"user"`
+}
+
+func toWorkspaceResponse(workspace domain.Workspace, role domain.WorkspaceRole, activeID domain.ID) WorkspaceResponse {
+	return WorkspaceResponse{
+		ID:        workspace.ID().String(),
+		Name:      workspace.Name().String(),
+		Personal:  workspace.IsPersonal(),
+		Role:      role.String(),
+		Active:    workspace.ID() == activeID,
+		CreatedAt: workspace.CreatedAt().String(),
+	}
+}
+
+func toWorkspaceMemberResponse(user domain.User, member domain.WorkspaceMember) WorkspaceMemberResponse {
+	return WorkspaceMemberResponse{
+		UserID:   user.ID().String(),
+		Email:    user.Email().String(),
+		Name:     user.AuthorName().String(),
+		Role:     member.Role().String(),
+		JoinedAt: member.JoinedAt().String(),
+	}
+}
+
+func withActiveWorkspace(user domain.User, workspaceID domain.ID) domain.User {
+	return domain.NewUser(
+		user.ID(),
+		user.Email(),
+		user.AuthorName(),
+		user.UITheme(),
+		user.PasswordHash(),
+		user.EmailVerified(),
+		workspaceID,
+	)
+}
+
+// 新規ユーザーを保存し、個人ワークスペースを作成してアクティブにする
+func saveNewUser(tx *gorm.DB, ctx context.Context, user domain.User) (domain.User, error) {
+	userRepo := gormrepo.NewUserRepository(tx, ctx)
+	saved, err := userRepo.Save(user)
+	if err != nil {
+		return domain.User{}, err
+	}
+	workspace, err := gormrepo.CreatePersonalWorkspace(tx, ctx, saved.ID())
+	if err != nil {
+		return domain.User{}, err
+	}
+	return userRepo.Save(withActiveWorkspace(saved, workspace.ID()))
+}
+
+// 認証ユーザーが操作対象とするワークスペースを返す
+// JWTのwidクレームを優先し、パーソナルアクセストークンなどwidがない場合は最後に選択したワークスペースを使う
+func activeWorkspaceID(c *gin.Context, db *gorm.DB, userID domain.ID) (domain.ID, error) {
+	if workspaceID, err := domain.NewID(c.GetString("workspace_id")); err == nil {
+		return workspaceID, nil
+	}
+	user, err := gormrepo.NewUserRepository(db, c.Request.Context()).Find(userID)
+	if err != nil {
+		return domain.ID{}, err
+	}
+	if !user.ActiveWorkspaceID().IsNil() {
+		return user.ActiveWorkspaceID(), nil
+	}
+	workspace, err := gormrepo.NewWorkspaceRepository(db, c.Request.Context()).FindPersonal(userID)
+	if err != nil {
+		return domain.ID{}, err
+	}
+	return workspace.ID(), nil
+}
+
+// パスパラメータのワークスペースについて、認証ユーザーのメンバー情報を取得する
+// メンバーでない場合はワークスペースの存在を明かさないよう404を返す
+func findWorkspaceMembership(c *gin.Context, db *gorm.DB) (domain.Workspace, domain.WorkspaceMember, bool) {
+	userID, err := domain.NewID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return domain.Workspace{}, domain.WorkspaceMember{}, false
+	}
+	workspaceID, err := domain.NewID(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+		return domain.Workspace{}, domain.WorkspaceMember{}, false
+	}
+
+	member, err := gormrepo.NewWorkspaceMemberRepository(db, c.Request.Context()).Find(workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+			return domain.Workspace{}, domain.WorkspaceMember{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspace"})
+		return domain.Workspace{}, domain.WorkspaceMember{}, false
+	}
+	workspace, err := gormrepo.NewWorkspaceRepository(db, c.Request.Context()).Find(workspaceID)
+	if err != nil {
+		if errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+			return domain.Workspace{}, domain.WorkspaceMember{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspace"})
+		return domain.Workspace{}, domain.WorkspaceMember{}, false
+	}
+	return workspace, member, true
+}
+
+// 所属しているワークスペース一覧
+// 個人ワークスペースを先頭に返す
+func NewListWorkspacesHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaceRepo := gormrepo.NewWorkspaceRepository(db, c.Request.Context())
+		memberRepo := gormrepo.NewWorkspaceMemberRepository(db, c.Request.Context())
+
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		activeID, err := activeWorkspaceID(c, db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspaces"})
+			return
+		}
+
+		workspaces, err := workspaceRepo.FindByUserID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspaces"})
+			return
+		}
+
+		resp := ListWorkspacesResponse{Workspaces: make([]WorkspaceResponse, 0, len(workspaces))}
+		for _, workspace := range workspaces {
+			member, err := memberRepo.Find(workspace.ID(), userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspaces"})
+				return
+			}
+			resp.Workspaces = append(resp.Workspaces, toWorkspaceResponse(workspace, member.Role(), activeID))
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// ワークスペース作成
+// 作成者はownerとして登録される
+func NewCreateWorkspaceHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateWorkspaceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		name, err := domain.NewWorkspaceName(req.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		userID, err := domain.NewID(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		activeID, err := activeWorkspaceID(c, db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
+			return
+		}
+
+		var saved domain.Workspace
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			saved, err = gormrepo.NewWorkspaceRepository(tx, c.Request.Context()).Save(domain.NewWorkspace(
+				domain.GenerateID(),
+				name,
+				domain.ID{},
+				domain.NewCreatedAtNow(),
+			))
+			if err != nil {
+				return err
+			}
+			_, err = gormrepo.NewWorkspaceMemberRepository(tx, c.Request.Context()).Save(domain.NewWorkspaceMember(
+				saved.ID(),
+				userID,
+				domain.OwnerWorkspaceRole(),
+				domain.NewCreatedAtNow(),
+			))
+			return err
+		})
+		if err != nil {
+			slog.Error("failed to create workspace", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, toWorkspaceResponse(saved, domain.OwnerWorkspaceRole(), activeID))
+	}
+}
+
+// ワークスペースのメンバー一覧
+func NewListWorkspaceMembersHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		memberRepo := gormrepo.NewWorkspaceMemberRepository(db, c.Request.Context())
+
+		workspace, _, ok := findWorkspaceMembership(c, db)
+		if !ok {
+			return
+		}
+
+		members, err := memberRepo.FindByWorkspaceID(workspace.ID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+			return
+		}
+
+		resp := ListWorkspaceMembersResponse{Members: make([]WorkspaceMemberResponse, 0, len(members))}
+		for _, member := range members {
+			user, err := userRepo.Find(member.UserID())
+			if err != nil {
+				// 退会済みのユーザーは一覧に含めない
+				if errors.Is(err, domain.ErrEntityNotFound) {
+					continue
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+				return
+			}
+			resp.Members = append(resp.Members, toWorkspaceMemberResponse(user, member))
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// ワークスペースへのメンバー招待
+// 既にメンバーのユーザーを指定した場合は権限を変更する
+// adminはownerの付与・変更ができない
+func NewAddWorkspaceMemberHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+		memberRepo := gormrepo.NewWorkspaceMemberRepository(db, c.Request.Context())
+
+		var req AddWorkspaceMemberRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		role, err := domain.NewWorkspaceRole(req.Role)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		email, err := domain.NewEmail(req.Email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		workspace, self, ok := findWorkspaceMembership(c, db)
+		if !ok {
+			return
+		}
+		if !self.Role().CanManageMembers() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			return
+		}
+		if workspace.IsPersonal() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Members cannot be added to a personal workspace"})
+			return
+		}
+
+		user, err := userRepo.FindByEmail(email)
+		if err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+			return
+		}
+
+		current, err := memberRepo.Find(workspace.ID(), user.ID())
+		isMember := err == nil
+		if err != nil && !errors.Is(err, domain.ErrEntityNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+			return
+		}
+		if !self.Role().IsOwner() && (role.IsOwner() || (isMember && current.Role().IsOwner())) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can grant or change the owner role"})
+			return
+		}
+		if isMember && current.Role().IsOwner() && !role.IsOwner() {
+			owners, err := memberRepo.CountOwners(workspace.ID())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+				return
+			}
+			if owners <= 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A workspace must have at least one owner"})
+				return
+			}
+		}
+
+		joinedAt := domain.NewCreatedAtNow()
+		if isMember {
+			joinedAt = current.JoinedAt()
+		}
+		saved, err := memberRepo.Save(domain.NewWorkspaceMember(workspace.ID(), user.ID(), role, joinedAt))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toWorkspaceMemberResponse(user, saved))
+	}
+}
+
+// ワークスペースからのメンバー削除
+// owner・adminは他のメンバーを、それ以外のメンバーは自分自身のみ削除できる
+// 削除されたメンバーが作成したドキュメントはワークスペースに残る
+func NewRemoveWorkspaceMemberHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetID, err := domain.NewID(c.Param("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		workspace, self, ok := findWorkspaceMembership(c, db)
+		if !ok {
+			return
+		}
+		if workspace.IsPersonal() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Members cannot be removed from a personal workspace"})
+			return
+		}
+		if targetID != self.UserID() && !self.Role().CanManageMembers() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			memberRepo := gormrepo.NewWorkspaceMemberRepository(tx, c.Request.Context())
+			userRepo := gormrepo.NewUserRepository(tx, c.Request.Context())
+
+			target, err := memberRepo.Find(workspace.ID(), targetID)
+			if err != nil {
+				return err
+			}
+			if target.Role().IsOwner() {
+				if targetID != self.UserID() && !self.Role().IsOwner() {
+					return domain.ErrForbidden
+				}
+				owners, err := memberRepo.CountOwners(workspace.ID())
+				if err != nil {
+					return err
+				}
+				if owners <= 1 {
+					return errLastWorkspaceOwner
+				}
+			}
+			if err := memberRepo.Delete(workspace.ID(), targetID); err != nil {
+				return err
+			}
+
+			// 削除したワークスペースを選択中の場合は個人ワークスペースに戻す
+			user, err := userRepo.Find(targetID)
+			if err != nil {
+				if errors.Is(err, domain.ErrEntityNotFound) {
+					return nil
+				}
+				return err
+			}
+			if user.ActiveWorkspaceID() != workspace.ID() {
+				return nil
+			}
+			personal, err := gormrepo.NewWorkspaceRepository(tx, c.Request.Context()).FindPersonal(targetID)
+			if err != nil {
+				return err
+			}
+			_, err = userRepo.Save(withActiveWorkspace(user, personal.ID()))
+			return err
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, domain.ErrEntityNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+			case errors.Is(err, domain.ErrForbidden):
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can remove another owner"})
+			case errors.Is(err, errLastWorkspaceOwner):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A workspace must have at least one owner"})
+			default:
+				slog.Error("failed to remove workspace member", slog.Any("error", err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+			}
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+var errLastWorkspaceOwner = errors.New("last workspace owner")
+
+// アクティブなワークスペースの切り替え
+// 選択を保存し、widクレームを差し替えたアクセストークンを発行する
+func NewSwitchWorkspaceHandler(db *gorm.DB, tokens *token.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepo := gormrepo.NewUserRepository(db, c.Request.Context())
+
+		workspace, self, ok := findWorkspaceMembership(c, db)
+		if !ok {
+			return
+		}
+
+		user, err := userRepo.Find(self.UserID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to switch workspace"})
+			return
+		}
+		if user, err = userRepo.Save(withActiveWorkspace(user, workspace.ID())); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to switch workspace"})
+			return
+		}
+
+		authUser := toAuthUser(user)
+		accessToken, expiresIn, err := generateJWT(tokens, authUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, SwitchWorkspaceResponse{
+			AccessToken: accessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   expiresIn,
+			User:        *authUser,
+		})
+	}
+}
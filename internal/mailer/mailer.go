@@ -0,0 +1,14 @@
+package mailer
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailerはメール送信の抽象。本番ではSMTPMailer、開発ではLogMailerを使用する
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,74 @@
+package markdown
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// Cacheは変換結果を本文のハッシュごとに保持する。HTMLの合計サイズが上限を超えると最も長く使われていないものから捨てる
+// 複数のgoroutineから同時に使える
+type Cache struct {
+	mu            sync.Mutex
+	maxBytes      int
+	maxEntryBytes int
+	size          int // 保持しているHTMLの合計バイト数
+	entries       map[[sha256.Size]byte]*list.Element
+	order         *list.List // 先頭ほど最近使われたもの
+}
+
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	html string
+}
+
+// maxBytesはHTMLの合計サイズの上限で、0の場合はキャッシュせず毎回変換する
+// maxEntryBytesを超えるHTMLは、ほかの多くの結果を追い出さないようキャッシュしない
+func NewCache(maxBytes, maxEntryBytes int) *Cache {
+	return &Cache{
+		maxBytes:      maxBytes,
+		maxEntryBytes: min(maxEntryBytes, maxBytes),
+		entries:       make(map[[sha256.Size]byte]*list.Element),
+		order:         list.New(),
+	}
+}
+
+// RenderはRenderの結果を、同じ本文であればキャッシュから返す
+func (c *Cache) Render(src string) (string, error) {
+	key := sha256.Sum256([]byte(src))
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		html := element.Value.(*cacheEntry).html
+		c.mu.Unlock()
+		return html, nil
+	}
+	c.mu.Unlock()
+
+	// 変換中はロックを持たず、同じ本文を同時に変換した場合は先に登録した結果を残す
+	html, err := Render(src)
+	if err != nil {
+		return "", err
+	}
+	if len(html) > c.maxEntryBytes {
+		return html, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return html, nil
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, html: html})
+	c.size += len(html)
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= len(entry.html)
+	}
+	return html, nil
+}
@@ -0,0 +1,66 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// 生のHTMLは出力しない（goldmarkの既定）。共有リンクなど未認証の閲覧者にも返すため、有効にしないこと
+// コードのハイライトはメールでも表示できるよう、クラスではなくstyle属性で出力する
+var renderer = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+		extension.Footnote,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle("github"),
+			highlighting.WithFormatOptions(chromahtml.WithClasses(false)),
+		),
+	),
+)
+
+// 変換後のHTMLに残す要素・属性。変換結果をそのまま返さず、必ずこのポリシーを通す
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// 表の列の配置
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	// タスクリストのチェックボックス
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	p.AllowElements("input")
+	// 脚注
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnote-(ref|backref)$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes$`)).OnElements("div")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|backlink|endnotes)$`)).OnElements("a", "div")
+	// 見出しのアンカー。Headingsのアンカーと一致させる
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}\p{M}_-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	// コードのハイライト
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration").OnElements("span", "pre")
+	return p
+}
+
+// RenderはMarkdownをサニタイズしたHTMLに変換する
+// 見出しにはHeadingsと同じアンカーをid属性として付ける
+func Render(src string) (string, error) {
+	source := []byte(src)
+	doc := renderer.Parser().Parse(text.NewReader(source))
+	headings(doc, source)
+
+	var buf bytes.Buffer
+	if err := renderer.Renderer().Render(&buf, source, doc); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return policy.Sanitize(buf.String()), nil
+}
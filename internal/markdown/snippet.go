@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/iotassss/gizzmd/internal/wikilink"
+	"github.com/rivo/uniseg"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/text/unicode/norm"
+)
+
+// 途中で切った概要の末尾に付ける
+const ellipsis = "…"
+
+// 文の区切りで切ったときに、最低限残す文字数の割合。これより短くなる場合は文の途中で切る
+const minSentenceRatio = 2
+
+// Snippetは一覧表示用の概要として、本文の冒頭の文章をlimit文字以内で返す
+// 先頭のタイトルと同じ見出し、コードブロック・HTML・表・画像・脚注は除き、強調やリンクは文字列のみにする
+// 段落の区切りを含む空白は1つにまとめ、limitを超える場合は文の区切り、なければ書記素の区切りで切る
+func Snippet(title, src string, limit int) string {
+	source := []byte(src)
+	doc := renderer.Parser().Parse(text.NewReader(source))
+
+	var blocks []string
+	first := true
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindFencedCodeBlock, ast.KindCodeBlock, ast.KindHTMLBlock, ast.KindThematicBreak,
+			extast.KindTable, extast.KindFootnoteList:
+			return ast.WalkSkipChildren, nil
+		case ast.KindHeading, ast.KindParagraph, ast.KindTextBlock:
+			text := snippetText(n, source)
+			if text == "" {
+				return ast.WalkSkipChildren, nil
+			}
+			if first && n.Kind() == ast.KindHeading && sameTitle(text, title) {
+				first = false
+				return ast.WalkSkipChildren, nil
+			}
+			first = false
+			blocks = append(blocks, text)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return truncate(strings.Join(blocks, " "), limit)
+}
+
+// ブロック内の文字列から書式・画像を除き、空白をまとめる
+func snippetText(block ast.Node, src []byte) string {
+	var b strings.Builder
+	ast.Walk(block, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.AutoLink:
+			b.Write(n.Label(src))
+		case *ast.Image, *ast.RawHTML, *extast.FootnoteLink, *extast.TaskCheckBox:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(stripWikiLinks(b.String())), " ")
+}
+
+// [[タイトル|表示名]]は表示名、[[タイトル]]はタイトルにする。[[doc:ID]]はIDを表示しないよう除く
+func stripWikiLinks(s string) string {
+	links := wikilink.Parse(s)
+	if len(links) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, link := range links {
+		b.WriteString(s[last:link.Start])
+		switch _, isID := link.DocID(); {
+		case link.Label != "":
+			b.WriteString(link.Label)
+		case !isID:
+			b.WriteString(link.Target)
+		}
+		last = link.End
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// 全角・半角と大文字・小文字の違いは同じタイトルとみなす
+func sameTitle(heading, title string) bool {
+	title = strings.Join(strings.Fields(title), " ")
+	return strings.EqualFold(norm.NFKC.String(heading), norm.NFKC.String(title))
+}
+
+// limit文字以内に切る。文の区切りで切った結果が短すぎる場合は、書記素の区切りで切って末尾に…を付ける
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+
+	sentences, count := 0, 0
+	rest, state := s, -1
+	for len(rest) > 0 {
+		var sentence string
+		sentence, rest, state = uniseg.FirstSentenceInString(rest, state)
+		n := utf8.RuneCountInString(strings.TrimRight(sentence, " "))
+		if count+n > limit {
+			break
+		}
+		sentences += len(sentence)
+		count += utf8.RuneCountInString(sentence)
+	}
+	if cut := strings.TrimRight(s[:sentences], " "); utf8.RuneCountInString(cut)*minSentenceRatio >= limit {
+		return cut
+	}
+
+	limit -= utf8.RuneCountInString(ellipsis)
+	end, count := 0, 0
+	rest, state = s, -1
+	for len(rest) > 0 {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		count += utf8.RuneCountInString(cluster)
+		if count > limit {
+			break
+		}
+		end += len(cluster)
+	}
+	return strings.TrimRight(s[:end], " ") + ellipsis
+}
@@ -0,0 +1,146 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/text/unicode/norm"
+)
+
+// 見出しの文字がすべて記号などでアンカーにできない場合に使う名前
+const fallbackAnchor = "section"
+
+type Heading struct {
+	Level  int
+	Text   string // 書式を除いた見出しの文字列
+	Anchor string // 文書内で一意なアンカー。HTMLに変換したときの見出しのidと一致する
+	// 見出しの行から、次の同じかより上位の見出しの前までのバイト位置
+	Start int
+	End   int
+}
+
+// Headingsは本文の見出しを出現順に返す。文字を含まない見出しは除く
+func Headings(src string) []Heading {
+	source := []byte(src)
+	return headings(renderer.Parser().Parse(text.NewReader(source)), source)
+}
+
+// Sectionはアンカーの見出しから次の同じかより上位の見出しの前までのMarkdownを返す
+func Section(src, anchor string) (Heading, string, bool) {
+	for _, heading := range Headings(src) {
+		if heading.Anchor == anchor {
+			return heading, src[heading.Start:heading.End], true
+		}
+	}
+	return Heading{}, "", false
+}
+
+// 見出しを集め、アンカーを見出しのid属性に設定する
+func headings(doc ast.Node, src []byte) []Heading {
+	var result []Heading
+	anchors := newAnchorSet()
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		text := headingText(heading, src)
+		if text == "" || heading.Lines().Len() == 0 {
+			return ast.WalkSkipChildren, nil
+		}
+		anchor := anchors.add(text)
+		heading.SetAttributeString("id", []byte(anchor))
+		result = append(result, Heading{
+			Level:  heading.Level,
+			Text:   text,
+			Anchor: anchor,
+			Start:  lineStart(src, heading.Lines().At(0).Start),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+
+	for i := range result {
+		result[i].End = len(src)
+		for _, next := range result[i+1:] {
+			if next.Level <= result[i].Level {
+				result[i].End = next.Start
+				break
+			}
+		}
+	}
+	return result
+}
+
+func lineStart(src []byte, pos int) int {
+	for pos > 0 && src[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+// 見出しの文字列から強調・リンクなどの書式を除き、空白をまとめる
+func headingText(heading *ast.Heading, src []byte) string {
+	var b strings.Builder
+	ast.Walk(heading, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.AutoLink:
+			b.Write(n.Label(src))
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// 文書内のアンカー。同じアンカーには2つ目から-1、-2...を付ける
+type anchorSet struct {
+	used   map[string]bool
+	counts map[string]int
+}
+
+func newAnchorSet() *anchorSet {
+	return &anchorSet{used: make(map[string]bool), counts: make(map[string]int)}
+}
+
+func (s *anchorSet) add(text string) string {
+	base := slugify(text)
+	anchor := base
+	for s.used[anchor] {
+		s.counts[base]++
+		anchor = fmt.Sprintf("%s-%d", base, s.counts[base])
+	}
+	s.used[anchor] = true
+	return anchor
+}
+
+// 見出しをアンカーにする。日本語などの文字はそのまま残し、全角英数字は半角にする
+// 英字は小文字にし、空白は-に置き換え、それ以外の記号は除く
+func slugify(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKC.String(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) || r == '_' || r == '-':
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r):
+			b.WriteByte('-')
+		}
+	}
+	if b.Len() == 0 {
+		return fallbackAnchor
+	}
+	return b.String()
+}
@@ -1,15 +1,32 @@
 package middleware
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iotassss/gizzmd/internal/domain"
 	handler "github.com/iotassss/gizzmd/internal/handler"
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"github.com/iotassss/gizzmd/internal/securetoken"
+	"github.com/iotassss/gizzmd/internal/token"
+	"gorm.io/gorm"
 )
 
-// AuthMiddlewareはJWT認証を行うGin用ミドルウェアです。
-func AuthMiddleware() gin.HandlerFunc {
+const (
+	AuthMethodJWT = "jwt"
+	AuthMethodPAT = "pat"
+)
+
+// last_used_atの更新頻度の下限。リクエストごとの書き込みを避ける
+const lastUsedUpdateInterval = time.Minute
+
+// AuthMiddlewareはJWTまたはパーソナルアクセストークンによる認証を行うGin用ミドルウェアです。
+// ログアウト済み（jtiがブラックリストに登録済み）のJWTは拒否します。
+func AuthMiddleware(db *gorm.DB, tokens *token.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
 		if header == "" || !strings.HasPrefix(header, "Bearer ") {
@@ -17,12 +34,92 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 		tokenString := strings.TrimPrefix(header, "Bearer ")
-		user, err := handler.ValidateJWT(tokenString)
+
+		if strings.HasPrefix(tokenString, handler.PersonalAccessTokenPrefix) {
+			authenticatePersonalAccessToken(c, db, tokenString)
+			return
+		}
+
+		accessToken, err := handler.ValidateJWT(tokens, tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, handler.ErrorResponse{Message: "Invalid or expired token"})
+			return
+		}
+
+		revokedRepo := gormrepo.NewRevokedAccessTokenRepository(db, c.Request.Context())
+		revoked, err := revokedRepo.IsRevoked(accessToken.JTI)
 		if err != nil {
+			slog.Error("failed to check revoked access token", slog.Any("error", err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, handler.ErrorResponse{Message: "Failed to validate token"})
+			return
+		}
+		if revoked {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, handler.ErrorResponse{Message: "Invalid or expired token"})
 			return
 		}
-		c.Set("user_id", user.ID)
+
+		c.Set("user", &accessToken.User)
+		c.Set("user_id", accessToken.User.ID)
+		c.Set("workspace_id", accessToken.User.ActiveWorkspaceID)
+		c.Set("auth_method", AuthMethodJWT)
+		c.Set("token_scopes", domain.AllScopes())
+		c.Set("token_jti", accessToken.JTI)
+		c.Set("token_expires_at", accessToken.ExpiresAt)
+		c.Next()
+	}
+}
+
+func authenticatePersonalAccessToken(c *gin.Context, db *gorm.DB, tokenString string) {
+	tokenRepo := gormrepo.NewPersonalAccessTokenRepository(db, c.Request.Context())
+
+	pat, err := tokenRepo.FindByHash(securetoken.Hash(tokenString))
+	if err != nil {
+		if !errors.Is(err, domain.ErrEntityNotFound) {
+			slog.Error("failed to find personal access token", slog.Any("error", err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, handler.ErrorResponse{Message: "Failed to validate token"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, handler.ErrorResponse{Message: "Invalid or expired token"})
+		return
+	}
+	if pat.ExpiresAt().IsExpired() {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, handler.ErrorResponse{Message: "Invalid or expired token"})
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(pat.LastUsedAt()) >= lastUsedUpdateInterval {
+		if err := tokenRepo.TouchLastUsed(pat.ID(), now); err != nil {
+			slog.Error("failed to update personal access token last used", slog.Any("error", err))
+		}
+	}
+
+	c.Set("user_id", pat.UserID().String())
+	c.Set("auth_method", AuthMethodPAT)
+	c.Set("token_scopes", pat.Scopes())
+	c.Next()
+}
+
+// RequireScopeは認証済みトークンが指定の権限を持つ場合のみ通過させる
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, _ := c.Get("token_scopes")
+		if s, ok := scopes.(domain.Scopes); !ok || !s.Has(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, handler.ErrorResponse{Message: "Token does not have the required scope: " + scope})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSessionはログインセッション（JWT）でのみ許可する操作に使用する
+// トークン管理やパスワード変更をパーソナルアクセストークンから行えないようにする
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != AuthMethodJWT {
+			c.AbortWithStatusJSON(http.StatusForbidden, handler.ErrorResponse{Message: "This operation requires an interactive login session"})
+			return
+		}
 		c.Next()
 	}
 }
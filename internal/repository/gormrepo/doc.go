@@ -3,29 +3,42 @@ package gormrepo
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DocModel struct {
 	gorm.Model
-	ID        string    `gorm:"column:id;primaryKey;not null"`
-	Title     string    `gorm:"column:title;not null"`
-	Content   string    `gorm:"column:content;not null"`
-	Tags      string    `gorm:"column:tags;not null"`
-	Snippet   string    `gorm:"column:snippet;not null"`
-	AuthorID  string    `gorm:"column:author_id;not null"`
-	CreatedAt time.Time `gorm:"column:created_at;not null"`
-	EditedAt  time.Time `gorm:"column:edited_at;not null"`
+	ID string `gorm:"column:id;primaryKey;not null"`
+	// 日本語の本文は単語で区切られていないため、ngramパーサーで全文検索インデックスを作成する
+	Title    string `gorm:"column:title;not null;index:idx_docs_fulltext,class:FULLTEXT,option:WITH PARSER ngram"`
+	Content  string `gorm:"column:content;not null;index:idx_docs_fulltext,class:FULLTEXT,option:WITH PARSER ngram"`
+	Snippet  string `gorm:"column:snippet;not null"`
+	AuthorID string `gorm:"column:author_id;not null"`
+	// 未割り当ての既存データは空文字。起動時にBackfillPersonalWorkspacesで作成者の個人ワークスペースへ移す
+	WorkspaceID string    `gorm:"column:workspace_id;not null;default:'';index"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null"`
+	EditedAt    time.Time `gorm:"column:edited_at;not null"`
+	// リビジョン導入前のデータは空文字。作成者が編集したものとして扱う
+	EditedBy string `gorm:"column:edited_by;not null;default:''"`
+	Version  int    `gorm:"column:version;not null;default:1"`
+	// 最上位のドキュメントはNULL
+	ParentID *string `gorm:"column:parent_id;size:36;index:idx_docs_parent_position,priority:1"`
+	Position int     `gorm:"column:position;not null;default:0;index:idx_docs_parent_position,priority:2"`
 }
 
 func (DocModel) TableName() string {
 	return "docs"
 }
 
-func toDocDomain(model DocModel) (domain.Doc, error) {
+// タグはdoc_tagsテーブルからfindDocTagNamesで取得して渡す
+func toDocDomain(model DocModel, tagNames []string) (domain.Doc, error) {
 	id, err := domain.NewID(model.ID)
 	if err != nil {
 		return domain.Doc{}, err
@@ -35,7 +48,7 @@ func toDocDomain(model DocModel) (domain.Doc, error) {
 		return domain.Doc{}, err
 	}
 	content := domain.NewContent(model.Content)
-	tags, err := domain.NewTags(model.Tags)
+	tags, err := domain.NewTagList(tagNames)
 	if err != nil {
 		return domain.Doc{}, err
 	}
@@ -47,10 +60,28 @@ func toDocDomain(model DocModel) (domain.Doc, error) {
 	if err != nil {
 		return domain.Doc{}, err
 	}
+	var workspaceID domain.ID
+	if model.WorkspaceID != "" {
+		if workspaceID, err = domain.NewID(model.WorkspaceID); err != nil {
+			return domain.Doc{}, err
+		}
+	}
+	editedBy := authorId
+	if model.EditedBy != "" {
+		if editedBy, err = domain.NewID(model.EditedBy); err != nil {
+			return domain.Doc{}, err
+		}
+	}
+	var parentID domain.ID
+	if model.ParentID != nil {
+		if parentID, err = domain.NewID(*model.ParentID); err != nil {
+			return domain.Doc{}, err
+		}
+	}
 	createdAt := domain.NewCreatedAt(model.CreatedAt)
 	editedAt := domain.NewEditedAt(model.EditedAt)
 
-	return domain.NewDoc(id, title, content, tags, snippet, authorId, createdAt, editedAt), nil
+	return domain.NewDoc(id, workspaceID, title, content, tags, snippet, authorId, createdAt, editedAt, editedBy, model.Version, parentID, model.Position), nil
 }
 
 type DocRepository struct {
@@ -73,33 +104,126 @@ func (r *DocRepository) Find(id domain.ID) (domain.Doc, error) {
 		}
 		return domain.Doc{}, err
 	}
-	return toDocDomain(model)
+	tagNames, err := findDocTagNames(r.db.WithContext(r.ctx), []string{model.ID})
+	if err != nil {
+		return domain.Doc{}, err
+	}
+	return toDocDomain(model, tagNames[model.ID])
 }
 
-func (r *DocRepository) Save(doc domain.Doc) (domain.Doc, error) {
-	var existing DocModel
-	err := r.db.WithContext(r.ctx).First(&existing, "id = ?", doc.ID().String()).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return domain.Doc{}, err
+// タグを含めてドメインのドキュメントに変換する
+func toDocDomains(db *gorm.DB, models []DocModel) ([]domain.Doc, error) {
+	ids := make([]string, len(models))
+	for i, model := range models {
+		ids[i] = model.ID
 	}
+	tagNames, err := findDocTagNames(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]domain.Doc, len(models))
+	for i, model := range models {
+		doc, err := toDocDomain(model, tagNames[model.ID])
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+	return docs, nil
+}
 
+func (r *DocRepository) Save(doc domain.Doc) (domain.Doc, error) {
 	model := DocModel{
 		ID:        doc.ID().String(),
 		Title:     doc.Title().Value(),
 		Content:   doc.Content().Value(),
-		Tags:      doc.Tags().String(),
 		Snippet:   doc.Snippet().Value(),
 		AuthorID:  doc.AuthorId().String(),
 		CreatedAt: doc.CreatedAt().Value(),
 		EditedAt:  doc.EditedAt().Value(),
+		EditedBy:  doc.EditedBy().String(),
+		Version:   1,
+	}
+	if !doc.WorkspaceID().IsNil() {
+		model.WorkspaceID = doc.WorkspaceID().String()
 	}
-	if err == nil {
-		model.CreatedAt = existing.CreatedAt
-		model.UpdatedAt = existing.UpdatedAt
-		model.DeletedAt = existing.DeletedAt
+	if !doc.ParentID().IsNil() {
+		parentID := doc.ParentID().String()
+		model.ParentID = &parentID
 	}
 
-	if err := r.db.WithContext(r.ctx).Save(&model).Error; err != nil {
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		// 版数の確認から保存までの間に他の更新が割り込まないよう、行ロックを取る
+		// ゴミ箱に移したドキュメントを新規作成として上書きしないよう、ゴミ箱のものも含めて確認する
+		var existing DocModel
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&existing, "id = ?", doc.ID().String()).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		exists := err == nil
+		switch {
+		case exists && existing.DeletedAt.Valid:
+			return domain.ErrEntityNotFound
+		case exists && doc.Version() == 0:
+			// 新規作成のIDが既存のドキュメントと重なった
+			return domain.ErrVersionConflict
+		case !exists && doc.Version() > 0:
+			// 取得後に完全に削除された
+			return domain.ErrEntityNotFound
+		}
+		if exists {
+			if existing.Version != doc.Version() {
+				return domain.ErrVersionConflict
+			}
+			model.CreatedAt = existing.CreatedAt
+			model.UpdatedAt = existing.UpdatedAt
+			model.DeletedAt = existing.DeletedAt
+			model.Version = existing.Version + 1
+			// 階層の位置はMoveでのみ変更する
+			model.ParentID = existing.ParentID
+			model.Position = existing.Position
+		} else {
+			// 新規作成時は親の子の末尾に追加する
+			if err := lockDocTree(tx, model.WorkspaceID); err != nil {
+				return err
+			}
+			position, err := nextDocPosition(tx, model.WorkspaceID, model.ParentID)
+			if err != nil {
+				return err
+			}
+			model.Position = position
+		}
+
+		if err := tx.Save(&model).Error; err != nil {
+			return err
+		}
+		if err := replaceDocTags(tx, model.ID, doc.Tags().Values()); err != nil {
+			return err
+		}
+		if err := replaceDocLinks(tx, doc); err != nil {
+			return err
+		}
+		if err := replaceDocHeadings(tx, doc); err != nil {
+			return err
+		}
+		// 新しいタイトルへのリンク切れを結び付け、変更前のタイトルへのリンクを書き換える
+		if !exists || existing.Title != model.Title {
+			if err := relinkDocLinks(tx, doc); err != nil {
+				return err
+			}
+		}
+		if exists && existing.Title != model.Title {
+			if err := rewriteTitleLinks(tx, r.ctx, doc, existing.Title); err != nil {
+				return err
+			}
+		}
+		// 保存のたびに内容をリビジョンとして残す
+		return createDocRevision(tx, doc)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrVersionConflict) {
+			return domain.Doc{}, err
+		}
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return domain.Doc{}, domain.ErrEntityNotFound
 		}
@@ -109,22 +233,91 @@ func (r *DocRepository) Save(doc domain.Doc) (domain.Doc, error) {
 		return domain.Doc{}, err
 	}
 
-	return toDocDomain(model)
+	return toDocDomain(model, doc.Tags().Values())
+}
+
+// 全文検索の関連度を含む検索結果の行
+type docMatchRow struct {
+	DocModel
+	Score float64 `gorm:"column:score"`
+}
+
+// 全文インデックスのngramの長さ（MySQLのngram_token_sizeの既定値）。これより短い語は全文検索で一致しない
+const ngramTokenSize = 2
+
+// 全文検索で一致しない短い語を、タイトルか本文に含むドキュメントを探す条件
+const shortTermSQL = "(title LIKE ? OR content LIKE ?)"
+
+// 検索語をBOOLEAN MODEの検索式に変換する。ngramより短い語は含めない
+// 各語をフレーズとして扱うことで、演算子として解釈される記号を含んでいても語句そのものを検索する
+func fulltextBooleanQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.ReplaceAll(term, `"`, "")
+		if term == "" || isShortTerm(term) {
+			continue
+		}
+		parts = append(parts, `+"`+term+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+func isShortTerm(term string) bool {
+	return utf8.RuneCountInString(term) < ngramTokenSize
 }
 
-func (r *DocRepository) FindDocs(query domain.DocsQuery) ([]domain.Doc, int, error) {
+func shortTermArgs(term string) []any {
+	pattern := "%" + escapeLike(term) + "%"
+	return []any{pattern, pattern}
+}
+
+func (r *DocRepository) FindDocs(query domain.DocsQuery) ([]domain.DocMatch, int, error) {
 	db := r.db.WithContext(r.ctx)
 
-	var models []DocModel
+	var rows []docMatchRow
 	var total int64
 
+	// 閲覧できる範囲はauthz.DocPolicyと一致させる（ワークスペースのメンバー、作成者、または権限を付与されたユーザー）
+	viewerID := query.ViewerID().String()
+	sharedDocIDs := db.Model(&DocPermissionModel{}).Select("doc_id").Where("user_id = ?", viewerID)
 	queryDB := db.Model(&DocModel{})
+	if query.SharedOnly() {
+		queryDB = queryDB.Where("author_id <> ? AND id IN (?)", viewerID, sharedDocIDs)
+	} else {
+		isMember := db.Model(&WorkspaceMemberModel{}).Select("1").
+			Where("workspace_members.workspace_id = docs.workspace_id AND workspace_members.user_id = ?", viewerID)
+		queryDB = queryDB.
+			Where("workspace_id = ?", query.WorkspaceID().String()).
+			Where("EXISTS (?) OR author_id = ? OR id IN (?)", isMember, viewerID, sharedDocIDs)
+	}
 
-	if !query.Tags().IsEmpty() {
-		tags := query.Tags().Values()
-		for _, tag := range tags {
-			queryDB = queryDB.Where("tags LIKE ?", "%"+tag+"%")
+	// 検索インデックスの結果がある場合はそれで絞り込み、関連度も検索インデックスのものを使う
+	const match = "MATCH (title, content) AGAINST (? IN BOOLEAN MODE)"
+	var booleanQuery string
+	var hitIDs []string
+	hitScores := map[string]float64{}
+	if query.UsesSearchIndex() {
+		for _, hit := range query.SearchHits() {
+			hitIDs = append(hitIDs, hit.DocID().String())
+			hitScores[hit.DocID().String()] = hit.Score()
 		}
+		// 一致がない場合もIN (NULL)となり、結果は空になる
+		queryDB = queryDB.Where("id IN ?", hitIDs)
+	} else {
+		if booleanQuery = fulltextBooleanQuery(query.Text().Terms()); booleanQuery != "" {
+			queryDB = queryDB.Where(match, booleanQuery)
+		}
+		// 短い語は関連度に含めず、LIKEで絞り込む
+		for _, term := range query.Text().Terms() {
+			if term != "" && isShortTerm(term) {
+				queryDB = queryDB.Where(shortTermSQL, shortTermArgs(term)...)
+			}
+		}
+	}
+
+	for _, group := range query.Filter().Groups() {
+		sql, args := docConditionGroupSQL(group)
+		queryDB = queryDB.Where(sql, args...)
 	}
 
 	if !query.CreatedRange().IsEmpty() {
@@ -149,40 +342,334 @@ func (r *DocRepository) FindDocs(query domain.DocsQuery) ([]domain.Doc, int, err
 		return nil, 0, err
 	}
 
+	if booleanQuery != "" {
+		queryDB = queryDB.Select("docs.*, "+match+" AS score", booleanQuery)
+	} else {
+		queryDB = queryDB.Select("docs.*, 0 AS score")
+	}
+
 	orderBy := query.SortBy().Value()
+	if query.SortBy().IsRelevance() {
+		orderBy = domain.SortByCreatedAt
+		if booleanQuery != "" {
+			orderBy = "score"
+		}
+	}
 	if query.SortOrder().IsDesc() {
 		orderBy += " DESC"
 	} else {
 		orderBy += " ASC"
 	}
+	var order any = orderBy
+	// 検索インデックスの結果は関連度の高い順に並んでいるため、その順序で並べる
+	if query.SortBy().IsRelevance() && len(hitIDs) > 0 {
+		direction := " ASC"
+		if !query.SortOrder().IsDesc() {
+			direction = " DESC"
+		}
+		order = clause.OrderBy{Expression: clause.Expr{SQL: "FIELD(id, ?)" + direction, Vars: []any{hitIDs}, WithoutParentheses: true}}
+	}
 
-	if err := queryDB.Order(orderBy).
+	if err := queryDB.Order(order).
 		Offset(query.Offset()).
 		Limit(query.Limit().Value()).
-		Find(&models).Error; err != nil {
+		Scan(&rows).Error; err != nil {
 		return nil, 0, err
 	}
 
-	docs := make([]domain.Doc, len(models))
-	for i, model := range models {
-		doc, err := toDocDomain(model)
-		if err != nil {
-			return nil, 0, err
+	models := make([]DocModel, len(rows))
+	for i, row := range rows {
+		models[i] = row.DocModel
+	}
+	docs, err := toDocDomains(db, models)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matches := make([]domain.DocMatch, len(rows))
+	for i, row := range rows {
+		doc := docs[i]
+		score := row.Score
+		if query.UsesSearchIndex() {
+			score = hitScores[row.ID]
 		}
-		docs[i] = doc
+		matches[i] = domain.NewDocMatch(doc, score)
 	}
 
-	return docs, int(total), nil
+	return matches, int(total), nil
+}
+
+// 検索条件のいずれかを満たすWHERE句を返す
+func docConditionGroupSQL(group []domain.DocCondition) (string, []any) {
+	parts := make([]string, len(group))
+	var args []any
+	for i, condition := range group {
+		sql, conditionArgs := docConditionSQL(condition)
+		if condition.Negated() {
+			sql = "NOT (" + sql + ")"
+		}
+		parts[i] = "(" + sql + ")"
+		args = append(args, conditionArgs...)
+	}
+	return strings.Join(parts, " OR "), args
+}
+
+func docConditionSQL(condition domain.DocCondition) (string, []any) {
+	switch condition.Field() {
+	case domain.DocConditionTag:
+		return "EXISTS (SELECT 1 FROM doc_tags JOIN tags ON tags.id = doc_tags.tag_id WHERE doc_tags.doc_id = docs.id AND tags.name = ?)",
+			[]any{condition.Value()}
+	case domain.DocConditionAuthor:
+		return "author_id = ?", []any{condition.AuthorID().String()}
+	case domain.DocConditionCreated, domain.DocConditionUpdated:
+		column := "created_at"
+		if condition.Field() == domain.DocConditionUpdated {
+			column = "edited_at"
+		}
+		var parts []string
+		var args []any
+		if condition.From() != nil {
+			parts = append(parts, column+" >= ?")
+			args = append(args, *condition.From())
+		}
+		if condition.To() != nil {
+			parts = append(parts, column+" < ?")
+			args = append(args, *condition.To())
+		}
+		return strings.Join(parts, " AND "), args
+	default:
+		if condition.Value() != "" && isShortTerm(condition.Value()) {
+			return shortTermSQL, shortTermArgs(condition.Value())
+		}
+		return "MATCH (title, content) AGAINST (? IN BOOLEAN MODE)", []any{fulltextBooleanQuery([]string{condition.Value()})}
+	}
 }
 
 func (r *DocRepository) Delete(id domain.ID) error {
-	if err := r.db.WithContext(r.ctx).Delete(&DocModel{}, "id = ?", id.String()).Error; err != nil {
+	return r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var model DocModel
+		if err := tx.First(&model, "id = ?", id.String()).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrEntityNotFound
+			}
+			return err
+		}
+		return trashDoc(tx, model)
+	})
+}
+
+func (r *DocRepository) DeleteWithVersion(id domain.ID, version int) error {
+	return r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var model DocModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model, "id = ?", id.String()).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrEntityNotFound
+			}
+			return err
+		}
+		if model.Version != version {
+			return domain.ErrVersionConflict
+		}
+		return trashDoc(tx, model)
+	})
+}
+
+// ドキュメントをゴミ箱に移動し、子のドキュメントを親の子の末尾に移す
+func trashDoc(tx *gorm.DB, model DocModel) error {
+	if err := lockDocTree(tx, model.WorkspaceID); err != nil {
+		return err
+	}
+	if err := tx.Delete(&DocModel{}, "id = ?", model.ID).Error; err != nil {
+		return err
+	}
+
+	var childIDs []string
+	if err := tx.Model(&DocModel{}).Where("parent_id = ?", model.ID).
+		Order("position, created_at, id").
+		Pluck("id", &childIDs).Error; err != nil {
+		return err
+	}
+	if len(childIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&DocModel{}).Where("id IN ?", childIDs).
+		UpdateColumn("parent_id", model.ParentID).Error; err != nil {
+		return err
+	}
+	siblingIDs, err := findSiblingIDs(tx, model.WorkspaceID, model.ParentID, "")
+	if err != nil {
+		return err
+	}
+	// 子はfindSiblingIDsの結果に含まれるため、移した子が末尾に並ぶよう除いてから追加する
+	isChild := make(map[string]bool, len(childIDs))
+	for _, childID := range childIDs {
+		isChild[childID] = true
+	}
+	ordered := make([]string, 0, len(siblingIDs))
+	for _, siblingID := range siblingIDs {
+		if !isChild[siblingID] {
+			ordered = append(ordered, siblingID)
+		}
+	}
+	return renumberDocs(tx, append(ordered, childIDs...))
+}
+
+func (r *DocRepository) EachBatch(batchSize int, fn func(docs []domain.Doc) error) error {
+	var models []DocModel
+	db := r.db.WithContext(r.ctx)
+	return db.Order("id").FindInBatches(&models, batchSize, func(tx *gorm.DB, _ int) error {
+		docs, err := toDocDomains(db, models)
+		if err != nil {
+			return err
+		}
+		return fn(docs)
+	}).Error
+}
+
+func (r *DocRepository) FindTrashed(id domain.ID) (domain.TrashedDoc, error) {
+	var model DocModel
+	if err := r.db.WithContext(r.ctx).Unscoped().
+		First(&model, "id = ? AND deleted_at IS NOT NULL", id.String()).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.TrashedDoc{}, domain.ErrEntityNotFound
+		}
+		return domain.TrashedDoc{}, err
+	}
+	trashed, err := toTrashedDocDomains(r.db.WithContext(r.ctx), []DocModel{model})
+	if err != nil {
+		return domain.TrashedDoc{}, err
+	}
+	return trashed[0], nil
+}
+
+func (r *DocRepository) FindTrash(viewerID domain.ID, workspaceID domain.ID, page domain.Page, limit domain.Limit) ([]domain.TrashedDoc, int, error) {
+	db := r.db.WithContext(r.ctx)
+
+	// 復元できる範囲はauthz.DocPolicyでActionDeleteを許可される範囲と一致させる
+	// （作成者、ワークスペースのadmin以上、またはownerを付与されたユーザー）
+	isManager := db.Model(&WorkspaceMemberModel{}).Select("1").
+		Where("workspace_members.workspace_id = docs.workspace_id AND workspace_members.user_id = ?", viewerID.String()).
+		Where("workspace_members.role IN ?", []string{domain.WorkspaceRoleAdmin, domain.WorkspaceRoleOwner})
+	ownedDocIDs := db.Model(&DocPermissionModel{}).Select("doc_id").
+		Where("user_id = ? AND role = ?", viewerID.String(), domain.DocRoleOwner)
+	queryDB := db.Unscoped().Model(&DocModel{}).
+		Where("deleted_at IS NOT NULL AND workspace_id = ?", workspaceID.String()).
+		Where("author_id = ? OR EXISTS (?) OR id IN (?)", viewerID.String(), isManager, ownedDocIDs)
+
+	var total int64
+	if err := queryDB.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var models []DocModel
+	if err := queryDB.Order("deleted_at DESC").
+		Offset((page.Value() - 1) * limit.Value()).
+		Limit(limit.Value()).
+		Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	trashed, err := toTrashedDocDomains(db, models)
+	if err != nil {
+		return nil, 0, err
+	}
+	return trashed, int(total), nil
+}
+
+func (r *DocRepository) Restore(id domain.ID) error {
+	return r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var model DocModel
+		if err := tx.Unscoped().First(&model, "id = ? AND deleted_at IS NOT NULL", id.String()).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrEntityNotFound
+			}
+			return err
+		}
+		if err := lockDocTree(tx, model.WorkspaceID); err != nil {
+			return err
+		}
+
+		parentID := model.ParentID
+		if parentID != nil {
+			var count int64
+			if err := tx.Model(&DocModel{}).Where("id = ?", *parentID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				parentID = nil
+			}
+		}
+		position, err := nextDocPosition(tx, model.WorkspaceID, parentID)
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&DocModel{}).Where("id = ?", model.ID).
+			Updates(map[string]any{"deleted_at": nil, "parent_id": parentID, "position": position}).Error
+	})
+}
+
+func (r *DocRepository) Purge(id domain.ID) error {
+	return r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Delete(&DocModel{}, "id = ? AND deleted_at IS NOT NULL", id.String())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
 			return domain.ErrEntityNotFound
 		}
+		return deleteDocDependents(tx, []string{id.String()})
+	})
+}
+
+// 1回のPurgeTrashedBeforeで削除する最大件数。トランザクションが長時間ロックを保持しないようにする
+const purgeBatchSize = 500
+
+func (r *DocRepository) PurgeTrashedBefore(before time.Time) (int, error) {
+	var purged int
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		if err := tx.Unscoped().Model(&DocModel{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Limit(purgeBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Delete(&DocModel{}, "id IN ?", ids).Error; err != nil {
+			return err
+		}
+		purged = len(ids)
+		return deleteDocDependents(tx, ids)
+	})
+	return purged, err
+}
+
+// ドキュメントに紐づくデータを削除する
+func deleteDocDependents(tx *gorm.DB, docIDs []string) error {
+	for _, model := range []any{&DocRevisionModel{}, &DocPermissionModel{}, &ShareLinkModel{}, &DocTagModel{}, &DocHeadingModel{}} {
+		if err := tx.Delete(model, "doc_id IN ?", docIDs).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Delete(&DocLinkModel{}, "source_id IN ?", docIDs).Error; err != nil {
 		return err
 	}
-	return nil
+	// 削除したドキュメントへのリンクはリンク切れにする
+	return tx.Model(&DocLinkModel{}).Where("target_id IN ?", docIDs).UpdateColumn("target_id", nil).Error
+}
+
+func toTrashedDocDomains(db *gorm.DB, models []DocModel) ([]domain.TrashedDoc, error) {
+	docs, err := toDocDomains(db, models)
+	if err != nil {
+		return nil, err
+	}
+	trashed := make([]domain.TrashedDoc, len(models))
+	for i, model := range models {
+		trashed[i] = domain.NewTrashedDoc(docs[i], model.DeletedAt.Time)
+	}
+	return trashed, nil
 }
 
 func (r *DocRepository) SeedDummyDocs() error {
@@ -291,8 +778,11 @@ func (r *DocRepository) SeedDummyDocs() error {
 		if err != nil {
 			return err
 		}
-		err = r.Delete(id)
-		if err != nil {
+		// ゴミ箱に移してから完全に削除し、新規作成として保存し直す。初回の起動ではまだ存在しない
+		if err := r.Delete(id); err != nil && !errors.Is(err, domain.ErrEntityNotFound) {
+			return err
+		}
+		if err := r.Purge(id); err != nil && !errors.Is(err, domain.ErrEntityNotFound) {
 			return err
 		}
 
@@ -305,7 +795,7 @@ func (r *DocRepository) SeedDummyDocs() error {
 		if err != nil {
 			return err
 		}
-		snippet, err := domain.NewDocSnippet(domain.ExtractSnippet(content.Value()))
+		snippet, err := domain.NewDocSnippet(markdown.Snippet(title.Value(), content.Value(), domain.MaxDocSnippetLength))
 		if err != nil {
 			return err
 		}
@@ -315,7 +805,7 @@ func (r *DocRepository) SeedDummyDocs() error {
 		}
 		createdAt := domain.NewCreatedAt(d.created)
 		editedAt := domain.NewEditedAt(d.edited)
-		dummyDoc := domain.NewDoc(id, title, content, tags, snippet, authorId, createdAt, editedAt)
+		dummyDoc := domain.NewDoc(id, domain.ID{}, title, content, tags, snippet, authorId, createdAt, editedAt, authorId, 0, domain.ID{}, 0)
 		_, err = r.Save(dummyDoc)
 		if err != nil {
 			return err
@@ -0,0 +1,75 @@
+package gormrepo
+
+import (
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
+	"gorm.io/gorm"
+)
+
+// ドキュメントの目次。保存時に本文の見出しから作り直す
+type DocHeadingModel struct {
+	DocID    string `gorm:"column:doc_id;primaryKey;size:36"`
+	Position int    `gorm:"column:position;primaryKey"`
+	Level    int    `gorm:"column:level;not null"`
+	Text     string `gorm:"column:text;type:text;not null"`
+	Anchor   string `gorm:"column:anchor;type:text;not null"`
+}
+
+func (DocHeadingModel) TableName() string {
+	return "doc_headings"
+}
+
+// 本文から見出しを抽出して置き換える
+func replaceDocHeadings(tx *gorm.DB, doc domain.Doc) error {
+	if err := tx.Delete(&DocHeadingModel{}, "doc_id = ?", doc.ID().String()).Error; err != nil {
+		return err
+	}
+	headings := markdown.Headings(doc.Content().Value())
+	if len(headings) == 0 {
+		return nil
+	}
+	models := make([]DocHeadingModel, len(headings))
+	for i, heading := range headings {
+		models[i] = DocHeadingModel{
+			DocID:    doc.ID().String(),
+			Position: i,
+			Level:    heading.Level,
+			Text:     heading.Text,
+			Anchor:   heading.Anchor,
+		}
+	}
+	return tx.Create(&models).Error
+}
+
+func (r *DocRepository) FindHeadings(id domain.ID) ([]domain.DocHeading, error) {
+	var models []DocHeadingModel
+	if err := r.db.WithContext(r.ctx).
+		Where("doc_id = ?", id.String()).
+		Order("position").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	headings := make([]domain.DocHeading, len(models))
+	for i, model := range models {
+		headings[i] = domain.NewDocHeading(model.Level, model.Text, model.Anchor)
+	}
+	return headings, nil
+}
+
+// RebuildHeadingsで1回のトランザクションで処理する件数
+const rebuildHeadingsBatchSize = 500
+
+// RebuildHeadingsはすべてのドキュメントの目次を本文から作り直す。doc_headingsテーブルを追加したときの移行に使う
+func (r *DocRepository) RebuildHeadings() error {
+	db := r.db.WithContext(r.ctx)
+	return r.EachBatch(rebuildHeadingsBatchSize, func(docs []domain.Doc) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, doc := range docs {
+				if err := replaceDocHeadings(tx, doc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
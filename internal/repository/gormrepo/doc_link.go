@@ -0,0 +1,274 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/iotassss/gizzmd/internal/authz"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
+	"github.com/iotassss/gizzmd/internal/wikilink"
+	"gorm.io/gorm"
+)
+
+// ドキュメント本文中のリンク。リンク元の保存時に作り直す
+type DocLinkModel struct {
+	SourceID string `gorm:"column:source_id;primaryKey;size:36"`
+	// 本文中のリンク先の指定。タイトルは大文字・小文字を区別して比較する
+	Target string `gorm:"column:target;primaryKey;type:varchar(255) COLLATE utf8mb4_bin;index"`
+	// 解決したリンク先。リンク切れの場合はNULL
+	TargetID *string `gorm:"column:target_id;size:36;index"`
+	// 本文中で最初に出現した順序
+	Position int `gorm:"column:position;not null"`
+}
+
+func (DocLinkModel) TableName() string {
+	return "doc_links"
+}
+
+// 本文からリンクを抽出し、リンク先を解決して置き換える
+func replaceDocLinks(tx *gorm.DB, doc domain.Doc) error {
+	renamed, err := findRenamedLinkTargets(tx, doc.ID())
+	if err != nil {
+		return err
+	}
+	if err := tx.Delete(&DocLinkModel{}, "source_id = ?", doc.ID().String()).Error; err != nil {
+		return err
+	}
+
+	var models []DocLinkModel
+	seen := make(map[string]bool)
+	for _, link := range wikilink.Parse(doc.Content().Value()) {
+		if seen[link.Target] {
+			continue
+		}
+		seen[link.Target] = true
+		targetID, ok := renamed[link.Target]
+		if !ok {
+			var err error
+			if targetID, err = resolveDocLink(tx, doc.WorkspaceID(), link); err != nil {
+				return err
+			}
+		}
+		models = append(models, DocLinkModel{
+			SourceID: doc.ID().String(),
+			Target:   link.Target,
+			TargetID: targetID,
+			Position: len(models),
+		})
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	return tx.Create(&models).Error
+}
+
+// リンク元の本文に残っている、タイトルを変更したドキュメントへのリンクを返す。キーは本文中のリンク先の指定
+// 編集できないなどの理由で書き換えなかったリンクは、変更前のタイトルで解決し直さず同じドキュメントに結び付けたままにする
+func findRenamedLinkTargets(tx *gorm.DB, sourceID domain.ID) (map[string]*string, error) {
+	var rows []struct {
+		Target   string
+		TargetID string
+	}
+	if err := tx.Model(&DocLinkModel{}).
+		Select("doc_links.target, doc_links.target_id").
+		Joins("JOIN docs ON docs.id = doc_links.target_id AND docs.deleted_at IS NULL").
+		Where("doc_links.source_id = ? AND docs.title COLLATE utf8mb4_bin <> doc_links.target", sourceID.String()).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]*string, len(rows))
+	for _, row := range rows {
+		if _, isID := (wikilink.Link{Target: row.Target}).DocID(); isID {
+			continue
+		}
+		renamed[row.Target] = &row.TargetID
+	}
+	return renamed, nil
+}
+
+// リンク先のドキュメントIDを返す。見つからない場合はnil
+// タイトルは同じワークスペースのゴミ箱にないドキュメントから探し、同じタイトルが複数ある場合は最も古いものにする
+func resolveDocLink(tx *gorm.DB, workspaceID domain.ID, link wikilink.Link) (*string, error) {
+	var ids []string
+	if id, ok := link.DocID(); ok {
+		// ゴミ箱のドキュメントも結び付けておき、復元したときにリンクが戻るようにする
+		if err := tx.Unscoped().Model(&DocModel{}).Where("id = ?", id.String()).
+			Limit(1).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+	} else if err := tx.Model(&DocModel{}).
+		Where("workspace_id = ? AND title COLLATE utf8mb4_bin = ?", workspaceID.String(), link.Target).
+		Order("created_at, id").
+		Limit(1).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return &ids[0], nil
+}
+
+// 同じワークスペースの、docのタイトルへのリンク切れをdocに結び付ける
+// ゴミ箱のドキュメントに結び付いたままのリンクもリンク切れとして扱い、docに付け替える
+func relinkDocLinks(tx *gorm.DB, doc domain.Doc) error {
+	// doc:IDと同じ形のタイトルは、IDでのリンクと区別できないため結び付けない
+	if _, isID := (wikilink.Link{Target: doc.Title().Value()}).DocID(); isID {
+		return nil
+	}
+	sourceIDs := tx.Model(&DocModel{}).Select("id").Where("workspace_id = ?", doc.WorkspaceID().String())
+	trashedIDs := tx.Unscoped().Model(&DocModel{}).Select("id").Where("deleted_at IS NOT NULL")
+	return tx.Model(&DocLinkModel{}).
+		Where("target = ? AND source_id IN (?)", doc.Title().Value(), sourceIDs).
+		Where("target_id IS NULL OR target_id IN (?)", trashedIDs).
+		UpdateColumn("target_id", doc.ID().String()).Error
+}
+
+// docにoldTitleでリンクしているドキュメントの本文を、変更後のタイトルへのリンクに書き換えて保存する
+// 書き換えはdocを編集したユーザーによる保存として、リンク元の版数を上げてリビジョンを残す
+// そのユーザーが編集できないリンク元と、変更後のタイトルがリンクに書けない場合は書き換えず、リンクはtarget_idで結び付いたままにする（findRenamedLinkTargets）
+func rewriteTitleLinks(tx *gorm.DB, ctx context.Context, doc domain.Doc, oldTitle string) error {
+	if !wikilink.IsLinkableTitle(doc.Title().Value()) {
+		return nil
+	}
+	var sourceIDs []string
+	if err := tx.Model(&DocLinkModel{}).
+		Where("target_id = ? AND target = ? AND source_id <> ?", doc.ID().String(), oldTitle, doc.ID().String()).
+		Order("source_id").
+		Pluck("source_id", &sourceIDs).Error; err != nil {
+		return err
+	}
+
+	docRepo := NewDocRepository(tx, ctx)
+	policy := authz.NewDocPolicy(NewDocPermissionRepository(tx, ctx), NewWorkspaceMemberRepository(tx, ctx))
+	for _, sourceID := range sourceIDs {
+		id, err := domain.NewID(sourceID)
+		if err != nil {
+			return err
+		}
+		source, err := docRepo.Find(id)
+		if err != nil {
+			// ゴミ箱のドキュメントは書き換えない
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				continue
+			}
+			return err
+		}
+		if err := policy.Authorize(doc.EditedBy(), source, authz.ActionUpdate); err != nil {
+			if errors.Is(err, domain.ErrEntityNotFound) || errors.Is(err, domain.ErrForbidden) {
+				continue
+			}
+			return err
+		}
+		rewritten, ok := wikilink.RenameTitle(source.Content().Value(), oldTitle, doc.Title().Value())
+		if !ok {
+			continue
+		}
+		snippet, _ := domain.NewDocSnippet(markdown.Snippet(source.Title().Value(), rewritten, domain.MaxDocSnippetLength))
+		if _, err := docRepo.Save(domain.NewDoc(
+			source.ID(),
+			source.WorkspaceID(),
+			source.Title(),
+			domain.NewContent(rewritten),
+			source.Tags(),
+			snippet,
+			source.AuthorId(),
+			source.CreatedAt(),
+			domain.NewEditedAtNow(),
+			doc.EditedBy(),
+			source.Version(),
+			source.ParentID(),
+			source.Position(),
+		)); err != nil {
+			// 同時にゴミ箱に移されたものは書き換えない
+			if errors.Is(err, domain.ErrEntityNotFound) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+type DocLinkRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewDocLinkRepository(db *gorm.DB, ctx context.Context) *DocLinkRepository {
+	return &DocLinkRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+var _ domain.DocLinkRepository = (*DocLinkRepository)(nil)
+
+func (r *DocLinkRepository) FindOutlinks(sourceID domain.ID) ([]domain.DocLink, error) {
+	db := r.db.WithContext(r.ctx)
+	var rows []DocLinkModel
+	if err := db.Where("source_id = ?", sourceID.String()).Order("position").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var targetIDs []string
+	for _, row := range rows {
+		if row.TargetID != nil {
+			targetIDs = append(targetIDs, *row.TargetID)
+		}
+	}
+	var models []DocModel
+	if len(targetIDs) > 0 {
+		if err := db.Where("id IN ?", targetIDs).Find(&models).Error; err != nil {
+			return nil, err
+		}
+	}
+	docs, err := toDocDomains(db, models)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]domain.Doc, len(docs))
+	for _, doc := range docs {
+		targets[doc.ID().String()] = doc
+	}
+
+	links := make([]domain.DocLink, len(rows))
+	for i, row := range rows {
+		var target domain.Doc
+		if row.TargetID != nil {
+			target = targets[*row.TargetID]
+		}
+		links[i] = domain.NewDocLink(row.Target, target)
+	}
+	return links, nil
+}
+
+func (r *DocLinkRepository) FindBacklinks(targetID domain.ID) ([]domain.Doc, error) {
+	db := r.db.WithContext(r.ctx)
+	var models []DocModel
+	if err := db.
+		Where("id IN (?)", db.Model(&DocLinkModel{}).Select("source_id").Where("target_id = ?", targetID.String())).
+		Order("title, id").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return toDocDomains(db, models)
+}
+
+// Rebuildで1回のトランザクションで処理する件数
+const rebuildLinksBatchSize = 500
+
+// Rebuildはすべてのドキュメントの本文からリンクを作り直す。doc_linksテーブルを追加したときの移行に使う
+func (r *DocLinkRepository) Rebuild() error {
+	db := r.db.WithContext(r.ctx)
+	return NewDocRepository(db, r.ctx).EachBatch(rebuildLinksBatchSize, func(docs []domain.Doc) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, doc := range docs {
+				if err := replaceDocLinks(tx, doc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DocPermissionModel struct {
+	DocID     string    `gorm:"column:doc_id;primaryKey;not null;size:36"`
+	UserID    string    `gorm:"column:user_id;primaryKey;not null;size:36;index"`
+	Role      string    `gorm:"column:role;not null;size:16"`
+	GrantedBy string    `gorm:"column:granted_by;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+}
+
+func (DocPermissionModel) TableName() string {
+	return "doc_permissions"
+}
+
+func toDocPermissionDomain(model DocPermissionModel) (domain.DocPermission, error) {
+	docID, err := domain.NewID(model.DocID)
+	if err != nil {
+		return domain.DocPermission{}, err
+	}
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.DocPermission{}, err
+	}
+	role, err := domain.NewDocRole(model.Role)
+	if err != nil {
+		return domain.DocPermission{}, err
+	}
+	grantedBy, err := domain.NewID(model.GrantedBy)
+	if err != nil {
+		return domain.DocPermission{}, err
+	}
+	return domain.NewDocPermission(docID, userID, role, grantedBy, domain.NewCreatedAt(model.CreatedAt)), nil
+}
+
+type DocPermissionRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewDocPermissionRepository(db *gorm.DB, ctx context.Context) *DocPermissionRepository {
+	return &DocPermissionRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *DocPermissionRepository) Find(docID domain.ID, userID domain.ID) (domain.DocPermission, error) {
+	var model DocPermissionModel
+	if err := r.db.WithContext(r.ctx).
+		First(&model, "doc_id = ? AND user_id = ?", docID.String(), userID.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.DocPermission{}, domain.ErrEntityNotFound
+		}
+		return domain.DocPermission{}, err
+	}
+	return toDocPermissionDomain(model)
+}
+
+func (r *DocPermissionRepository) FindByDocID(docID domain.ID) ([]domain.DocPermission, error) {
+	var models []DocPermissionModel
+	if err := r.db.WithContext(r.ctx).
+		Where("doc_id = ?", docID.String()).
+		Order("created_at ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	permissions := make([]domain.DocPermission, len(models))
+	for i, model := range models {
+		permission, err := toDocPermissionDomain(model)
+		if err != nil {
+			return nil, err
+		}
+		permissions[i] = permission
+	}
+	return permissions, nil
+}
+
+func (r *DocPermissionRepository) FindDocIDsByUserID(userID domain.ID) ([]domain.ID, error) {
+	var values []string
+	if err := r.db.WithContext(r.ctx).
+		Model(&DocPermissionModel{}).
+		Where("user_id = ?", userID.String()).
+		Pluck("doc_id", &values).Error; err != nil {
+		return nil, err
+	}
+
+	docIDs := make([]domain.ID, len(values))
+	for i, value := range values {
+		docID, err := domain.NewID(value)
+		if err != nil {
+			return nil, err
+		}
+		docIDs[i] = docID
+	}
+	return docIDs, nil
+}
+
+func (r *DocPermissionRepository) Save(permission domain.DocPermission) (domain.DocPermission, error) {
+	model := DocPermissionModel{
+		DocID:     permission.DocID().String(),
+		UserID:    permission.UserID().String(),
+		Role:      permission.Role().Value(),
+		GrantedBy: permission.GrantedBy().String(),
+		CreatedAt: permission.CreatedAt().Value(),
+	}
+	// 付与済みの場合は権限のみ更新し、最初に共有した日時は保持する
+	if err := r.db.WithContext(r.ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"role", "granted_by", "updated_at"}),
+	}).Create(&model).Error; err != nil {
+		return domain.DocPermission{}, err
+	}
+	return r.Find(permission.DocID(), permission.UserID())
+}
+
+func (r *DocPermissionRepository) Delete(docID domain.ID, userID domain.ID) error {
+	result := r.db.WithContext(r.ctx).
+		Delete(&DocPermissionModel{}, "doc_id = ? AND user_id = ?", docID.String(), userID.String())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrEntityNotFound
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type DocRevisionModel struct {
+	ID        string    `gorm:"column:id;primaryKey;not null;size:36"`
+	DocID     string    `gorm:"column:doc_id;not null;size:36;uniqueIndex:idx_doc_revisions_doc_number"`
+	Number    int       `gorm:"column:number;not null;uniqueIndex:idx_doc_revisions_doc_number"`
+	Title     string    `gorm:"column:title;not null"`
+	Content   string    `gorm:"column:content;not null"`
+	Tags      string    `gorm:"column:tags;not null"`
+	EditedBy  string    `gorm:"column:edited_by;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+func (DocRevisionModel) TableName() string {
+	return "doc_revisions"
+}
+
+func toDocRevisionDomain(model DocRevisionModel) (domain.DocRevision, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.DocRevision{}, err
+	}
+	docID, err := domain.NewID(model.DocID)
+	if err != nil {
+		return domain.DocRevision{}, err
+	}
+	title, err := domain.NewDocTitle(model.Title)
+	if err != nil {
+		return domain.DocRevision{}, err
+	}
+	tags, err := domain.NewTags(model.Tags)
+	if err != nil {
+		return domain.DocRevision{}, err
+	}
+	editedBy, err := domain.NewID(model.EditedBy)
+	if err != nil {
+		return domain.DocRevision{}, err
+	}
+	return domain.NewDocRevision(
+		id,
+		docID,
+		model.Number,
+		title,
+		domain.NewContent(model.Content),
+		tags,
+		editedBy,
+		domain.NewCreatedAt(model.CreatedAt),
+	), nil
+}
+
+type DocRevisionRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewDocRevisionRepository(db *gorm.DB, ctx context.Context) *DocRevisionRepository {
+	return &DocRevisionRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *DocRevisionRepository) Find(docID domain.ID, id domain.ID) (domain.DocRevision, error) {
+	var model DocRevisionModel
+	if err := r.db.WithContext(r.ctx).
+		First(&model, "id = ? AND doc_id = ?", id.String(), docID.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.DocRevision{}, domain.ErrEntityNotFound
+		}
+		return domain.DocRevision{}, err
+	}
+	return toDocRevisionDomain(model)
+}
+
+func (r *DocRevisionRepository) FindByDocID(docID domain.ID) ([]domain.DocRevision, error) {
+	var models []DocRevisionModel
+	if err := r.db.WithContext(r.ctx).
+		Where("doc_id = ?", docID.String()).
+		Order("number DESC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	revisions := make([]domain.DocRevision, len(models))
+	for i, model := range models {
+		revision, err := toDocRevisionDomain(model)
+		if err != nil {
+			return nil, err
+		}
+		revisions[i] = revision
+	}
+	return revisions, nil
+}
+
+func (r *DocRevisionRepository) FindPrevious(revision domain.DocRevision) (domain.DocRevision, error) {
+	var model DocRevisionModel
+	if err := r.db.WithContext(r.ctx).
+		Where("doc_id = ? AND number < ?", revision.DocID().String(), revision.Number()).
+		Order("number DESC").
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.DocRevision{}, domain.ErrEntityNotFound
+		}
+		return domain.DocRevision{}, err
+	}
+	return toDocRevisionDomain(model)
+}
+
+// ドキュメントの現在の内容を新しいリビジョンとして記録する
+// 連番の重複はユニーク制約で防ぐため、DocRepository.Saveと同じトランザクション内で呼び出す
+func createDocRevision(tx *gorm.DB, doc domain.Doc) error {
+	var latest struct{ Number int }
+	if err := tx.Model(&DocRevisionModel{}).
+		Select("COALESCE(MAX(number), 0) AS number").
+		Where("doc_id = ?", doc.ID().String()).
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&DocRevisionModel{
+		ID:        domain.GenerateID().String(),
+		DocID:     doc.ID().String(),
+		Number:    latest.Number + 1,
+		Title:     doc.Title().Value(),
+		Content:   doc.Content().Value(),
+		Tags:      doc.Tags().String(),
+		EditedBy:  doc.EditedBy().String(),
+		CreatedAt: doc.EditedAt().Value(),
+	}).Error
+}
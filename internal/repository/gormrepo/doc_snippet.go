@@ -0,0 +1,36 @@
+package gormrepo
+
+import (
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/markdown"
+	"gorm.io/gorm"
+)
+
+// BackfillSnippetsで1回に読み込む件数
+const backfillSnippetsBatchSize = 500
+
+// BackfillSnippetsはゴミ箱を含むすべてのドキュメントの概要を本文から作り直し、変更した件数を返す
+// 編集ではないため、版数・編集日時は変えずリビジョンも作成しない
+func (r *DocRepository) BackfillSnippets() (int, error) {
+	var models []DocModel
+	db := r.db.WithContext(r.ctx)
+	updated := 0
+	err := db.Unscoped().Select("id", "title", "content", "snippet").Order("id").
+		FindInBatches(&models, backfillSnippetsBatchSize, func(_ *gorm.DB, _ int) error {
+			return db.Transaction(func(tx *gorm.DB) error {
+				for _, model := range models {
+					snippet := markdown.Snippet(model.Title, model.Content, domain.MaxDocSnippetLength)
+					if snippet == model.Snippet {
+						continue
+					}
+					if err := tx.Unscoped().Model(&DocModel{}).Where("id = ?", model.ID).
+						UpdateColumn("snippet", snippet).Error; err != nil {
+						return err
+					}
+					updated++
+				}
+				return nil
+			})
+		}).Error
+	return updated, err
+}
@@ -0,0 +1,209 @@
+package gormrepo
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 祖先をたどる最大の段数。データの不整合で循環していても無限に続けないようにする
+const maxDocTreeDepth = 1000
+
+// ワークスペースの行ロックを取り、同じワークスペースの階層の変更を直列化する
+// 同時に移動したドキュメントが互いの子になり循環するのを防ぐ
+func lockDocTree(tx *gorm.DB, workspaceID string) error {
+	if workspaceID == "" {
+		return nil
+	}
+	var workspace WorkspaceModel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&workspace, "id = ?", workspaceID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// 同じ親を持つゴミ箱にないドキュメント。parentIDがnilの場合はワークスペースの最上位
+func docSiblings(tx *gorm.DB, workspaceID string, parentID *string) *gorm.DB {
+	if parentID == nil {
+		return tx.Model(&DocModel{}).Where("workspace_id = ? AND parent_id IS NULL", workspaceID)
+	}
+	return tx.Model(&DocModel{}).Where("parent_id = ?", *parentID)
+}
+
+// 兄弟のIDを並び順に返す。excludeIDのドキュメントは除く
+func findSiblingIDs(tx *gorm.DB, workspaceID string, parentID *string, excludeID string) ([]string, error) {
+	var ids []string
+	err := docSiblings(tx, workspaceID, parentID).
+		Where("id <> ?", excludeID).
+		Order("position, created_at, id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// 兄弟の末尾の並び順を返す
+func nextDocPosition(tx *gorm.DB, workspaceID string, parentID *string) (int, error) {
+	var position int
+	err := docSiblings(tx, workspaceID, parentID).
+		Select("COALESCE(MAX(position) + 1, 0)").
+		Scan(&position).Error
+	return position, err
+}
+
+// idsの順に0から並び順を振り直す
+func renumberDocs(tx *gorm.DB, ids []string) error {
+	for i, id := range ids {
+		if err := tx.Model(&DocModel{}).Where("id = ? AND position <> ?", id, i).
+			UpdateColumn("position", i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parentIDのドキュメントとその祖先をルートから順に返す
+func findAncestorModels(tx *gorm.DB, parentID *string) ([]DocModel, error) {
+	var ancestors []DocModel
+	for next := parentID; next != nil; {
+		if len(ancestors) >= maxDocTreeDepth {
+			return nil, errors.New("document tree is too deep or contains a cycle")
+		}
+		var model DocModel
+		if err := tx.First(&model, "id = ?", *next).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, domain.ErrEntityNotFound
+			}
+			return nil, err
+		}
+		ancestors = append(ancestors, model)
+		next = model.ParentID
+	}
+	slices.Reverse(ancestors)
+	return ancestors, nil
+}
+
+func (r *DocRepository) FindAncestors(id domain.ID) ([]domain.Doc, error) {
+	db := r.db.WithContext(r.ctx)
+	var model DocModel
+	if err := db.First(&model, "id = ?", id.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrEntityNotFound
+		}
+		return nil, err
+	}
+	ancestors, err := findAncestorModels(db, model.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	return toDocDomains(db, ancestors)
+}
+
+type docTreeRow struct {
+	DocModel
+	HasChildren bool `gorm:"column:has_children"`
+}
+
+func (r *DocRepository) FindChildren(viewerID domain.ID, workspaceID domain.ID, parentID domain.ID) ([]domain.DocTreeNode, error) {
+	db := r.db.WithContext(r.ctx)
+
+	var parent *string
+	if !parentID.IsNil() {
+		value := parentID.String()
+		parent = &value
+	}
+
+	// 閲覧できる範囲はauthz.DocPolicyと一致させる（ワークスペースのメンバー、作成者、または権限を付与されたユーザー）
+	viewer := viewerID.String()
+	sharedDocIDs := db.Model(&DocPermissionModel{}).Select("doc_id").Where("user_id = ?", viewer)
+	isMember := db.Model(&WorkspaceMemberModel{}).Select("1").
+		Where("workspace_members.workspace_id = docs.workspace_id AND workspace_members.user_id = ?", viewer)
+
+	var rows []docTreeRow
+	if err := docSiblings(db, workspaceID.String(), parent).
+		Where("workspace_id = ?", workspaceID.String()).
+		Where("EXISTS (?) OR author_id = ? OR id IN (?)", isMember, viewer, sharedDocIDs).
+		Select("docs.*, EXISTS (SELECT 1 FROM docs AS children WHERE children.parent_id = docs.id AND children.deleted_at IS NULL) AS has_children").
+		Order("position, created_at, id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	models := make([]DocModel, len(rows))
+	for i, row := range rows {
+		models[i] = row.DocModel
+	}
+	docs, err := toDocDomains(db, models)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]domain.DocTreeNode, len(rows))
+	for i, row := range rows {
+		nodes[i] = domain.NewDocTreeNode(docs[i], row.HasChildren)
+	}
+	return nodes, nil
+}
+
+func (r *DocRepository) Move(id domain.ID, parentID domain.ID, position int) (domain.Doc, error) {
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var workspaceIDs []string
+		if err := tx.Model(&DocModel{}).Where("id = ?", id.String()).Pluck("workspace_id", &workspaceIDs).Error; err != nil {
+			return err
+		}
+		if len(workspaceIDs) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := lockDocTree(tx, workspaceIDs[0]); err != nil {
+			return err
+		}
+		// ロックを取るまでに他の移動で親・位置が変わっている場合があるため、ロックの中で読み直す
+		var model DocModel
+		if err := tx.First(&model, "id = ?", id.String()).Error; err != nil {
+			return err
+		}
+
+		var parent *string
+		if !parentID.IsNil() {
+			value := parentID.String()
+			parent = &value
+		}
+		ancestorModels, err := findAncestorModels(tx, parent)
+		if err != nil {
+			return err
+		}
+		docs, err := toDocDomains(tx, append(ancestorModels, model))
+		if err != nil {
+			return err
+		}
+		// 循環しないかはドメインで判定する
+		if _, err := docs[len(docs)-1].MoveTo(docs[:len(docs)-1], position); err != nil {
+			return err
+		}
+
+		// 移動元の兄弟を詰め、移動先の兄弟の間に挿入する
+		if oldSiblingIDs, err := findSiblingIDs(tx, model.WorkspaceID, model.ParentID, model.ID); err != nil {
+			return err
+		} else if err := renumberDocs(tx, oldSiblingIDs); err != nil {
+			return err
+		}
+		siblingIDs, err := findSiblingIDs(tx, model.WorkspaceID, parent, model.ID)
+		if err != nil {
+			return err
+		}
+		position = min(position, len(siblingIDs))
+		if err := tx.Model(&DocModel{}).Where("id = ?", model.ID).
+			UpdateColumns(map[string]any{"parent_id": parent, "position": position}).Error; err != nil {
+			return err
+		}
+		return renumberDocs(tx, slices.Insert(siblingIDs, position, model.ID))
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Doc{}, domain.ErrEntityNotFound
+		}
+		return domain.Doc{}, err
+	}
+	return r.Find(id)
+}
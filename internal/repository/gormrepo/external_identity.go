@@ -0,0 +1,85 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type ExternalIdentityModel struct {
+	ID        string    `gorm:"column:id;primaryKey;not null"`
+	UserID    string    `gorm:"column:user_id;not null;index"`
+	Issuer    string    `gorm:"column:issuer;not null;size:255;uniqueIndex:idx_external_identities_issuer_subject"`
+	Subject   string    `gorm:"column:subject;not null;size:255;uniqueIndex:idx_external_identities_issuer_subject"`
+	Email     string    `gorm:"column:email;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+func (ExternalIdentityModel) TableName() string {
+	return "external_identities"
+}
+
+func toExternalIdentityDomain(model ExternalIdentityModel) (domain.ExternalIdentity, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	email, err := domain.NewEmail(model.Email)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	return domain.NewExternalIdentity(
+		id,
+		userID,
+		model.Issuer,
+		model.Subject,
+		email,
+		domain.NewCreatedAt(model.CreatedAt),
+	), nil
+}
+
+type ExternalIdentityRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewExternalIdentityRepository(db *gorm.DB, ctx context.Context) *ExternalIdentityRepository {
+	return &ExternalIdentityRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *ExternalIdentityRepository) FindByIssuerSubject(issuer, subject string) (domain.ExternalIdentity, error) {
+	var model ExternalIdentityModel
+	if err := r.db.WithContext(r.ctx).
+		First(&model, "issuer = ? AND subject = ?", issuer, subject).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ExternalIdentity{}, domain.ErrEntityNotFound
+		}
+		return domain.ExternalIdentity{}, err
+	}
+	return toExternalIdentityDomain(model)
+}
+
+func (r *ExternalIdentityRepository) Save(identity domain.ExternalIdentity) (domain.ExternalIdentity, error) {
+	model := ExternalIdentityModel{
+		ID:        identity.ID().String(),
+		UserID:    identity.UserID().String(),
+		Issuer:    identity.Issuer(),
+		Subject:   identity.Subject(),
+		Email:     identity.Email().Value(),
+		CreatedAt: identity.CreatedAt().Value(),
+	}
+	if err := r.db.WithContext(r.ctx).Save(&model).Error; err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	return toExternalIdentityDomain(model)
+}
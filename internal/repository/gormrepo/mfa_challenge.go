@@ -0,0 +1,115 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type MFAChallengeModel struct {
+	ID        string    `gorm:"column:id;primaryKey;not null"`
+	UserID    string    `gorm:"column:user_id;not null;index"`
+	TokenHash string    `gorm:"column:token_hash;not null;uniqueIndex;size:64"`
+	Attempts  int       `gorm:"column:attempts;not null;default:0"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+func (MFAChallengeModel) TableName() string {
+	return "mfa_challenges"
+}
+
+func toMFAChallengeDomain(model MFAChallengeModel) (domain.MFAChallenge, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.MFAChallenge{}, err
+	}
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.MFAChallenge{}, err
+	}
+	return domain.NewMFAChallenge(
+		id,
+		userID,
+		model.TokenHash,
+		model.Attempts,
+		domain.NewExpiresAt(model.ExpiresAt),
+	), nil
+}
+
+type MFAChallengeRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewMFAChallengeRepository(db *gorm.DB, ctx context.Context) *MFAChallengeRepository {
+	return &MFAChallengeRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *MFAChallengeRepository) Save(challenge domain.MFAChallenge) (domain.MFAChallenge, error) {
+	db := r.db.WithContext(r.ctx)
+	// 2要素目が入力されずに終わったチャレンジが溜まらないよう、期限切れのものを併せて削除する
+	if err := db.Delete(&MFAChallengeModel{}, "expires_at <= ?", time.Now()).Error; err != nil {
+		return domain.MFAChallenge{}, err
+	}
+	model := MFAChallengeModel{
+		ID:        challenge.ID().String(),
+		UserID:    challenge.UserID().String(),
+		TokenHash: challenge.TokenHash(),
+		Attempts:  challenge.Attempts(),
+		ExpiresAt: challenge.ExpiresAt().Value(),
+	}
+	if err := db.Create(&model).Error; err != nil {
+		return domain.MFAChallenge{}, err
+	}
+	return toMFAChallengeDomain(model)
+}
+
+func (r *MFAChallengeRepository) FindByHash(tokenHash string) (domain.MFAChallenge, error) {
+	var model MFAChallengeModel
+	if err := r.db.WithContext(r.ctx).First(&model, "token_hash = ?", tokenHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.MFAChallenge{}, domain.ErrEntityNotFound
+		}
+		return domain.MFAChallenge{}, err
+	}
+	return toMFAChallengeDomain(model)
+}
+
+func (r *MFAChallengeRepository) RecordFailure(id domain.ID) (int, error) {
+	var attempts int
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&MFAChallengeModel{}).
+			Where("id = ?", id.String()).
+			Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+			return err
+		}
+		var model MFAChallengeModel
+		if err := tx.First(&model, "id = ?", id.String()).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrTokenInvalid
+			}
+			return err
+		}
+		attempts = model.Attempts
+		return nil
+	})
+	return attempts, err
+}
+
+func (r *MFAChallengeRepository) Consume(id domain.ID) error {
+	result := r.db.WithContext(r.ctx).Delete(&MFAChallengeModel{}, "id = ?", id.String())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrTokenInvalid
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OIDCLoginStateModel struct {
+	StateHash    string    `gorm:"column:state_hash;primaryKey;not null;size:64"`
+	Nonce        string    `gorm:"column:nonce;not null"`
+	CodeVerifier string    `gorm:"column:code_verifier;not null"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;not null;index"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+}
+
+func (OIDCLoginStateModel) TableName() string {
+	return "oidc_login_states"
+}
+
+type OIDCLoginStateRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewOIDCLoginStateRepository(db *gorm.DB, ctx context.Context) *OIDCLoginStateRepository {
+	return &OIDCLoginStateRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *OIDCLoginStateRepository) Save(state domain.OIDCLoginState) error {
+	db := r.db.WithContext(r.ctx)
+	// 完了しなかった認可リクエストが溜まらないよう、期限切れのものを併せて削除する
+	if err := db.Delete(&OIDCLoginStateModel{}, "expires_at <= ?", time.Now()).Error; err != nil {
+		return err
+	}
+	model := OIDCLoginStateModel{
+		StateHash:    state.StateHash(),
+		Nonce:        state.Nonce(),
+		CodeVerifier: state.CodeVerifier(),
+		ExpiresAt:    state.ExpiresAt().Value(),
+	}
+	return db.Create(&model).Error
+}
+
+func (r *OIDCLoginStateRepository) Consume(stateHash string) (domain.OIDCLoginState, error) {
+	var model OIDCLoginStateModel
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&model, "state_hash = ?", stateHash).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrTokenInvalid
+			}
+			return err
+		}
+		return tx.Delete(&model).Error
+	})
+	if err != nil {
+		return domain.OIDCLoginState{}, err
+	}
+
+	state := domain.NewOIDCLoginState(
+		model.StateHash,
+		model.Nonce,
+		model.CodeVerifier,
+		domain.NewExpiresAt(model.ExpiresAt),
+	)
+	if state.ExpiresAt().IsExpired() {
+		return domain.OIDCLoginState{}, domain.ErrTokenInvalid
+	}
+	return state, nil
+}
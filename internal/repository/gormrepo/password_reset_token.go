@@ -0,0 +1,99 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenModel struct {
+	ID        string     `gorm:"column:id;primaryKey;not null"`
+	UserID    string     `gorm:"column:user_id;not null;index"`
+	TokenHash string     `gorm:"column:token_hash;not null;uniqueIndex;size:64"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null"`
+}
+
+func (PasswordResetTokenModel) TableName() string {
+	return "password_reset_tokens"
+}
+
+func toPasswordResetTokenDomain(model PasswordResetTokenModel) (domain.PasswordResetToken, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.PasswordResetToken{}, err
+	}
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.PasswordResetToken{}, err
+	}
+	return domain.NewPasswordResetToken(id, userID, model.TokenHash, domain.NewExpiresAt(model.ExpiresAt)), nil
+}
+
+type PasswordResetTokenRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB, ctx context.Context) *PasswordResetTokenRepository {
+	return &PasswordResetTokenRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *PasswordResetTokenRepository) Save(token domain.PasswordResetToken) (domain.PasswordResetToken, error) {
+	model := PasswordResetTokenModel{
+		ID:        token.ID().String(),
+		UserID:    token.UserID().String(),
+		TokenHash: token.TokenHash(),
+		ExpiresAt: token.ExpiresAt().Value(),
+	}
+	if err := r.db.WithContext(r.ctx).Create(&model).Error; err != nil {
+		return domain.PasswordResetToken{}, err
+	}
+	return toPasswordResetTokenDomain(model)
+}
+
+func (r *PasswordResetTokenRepository) Consume(tokenHash string) (domain.ID, error) {
+	var userID domain.ID
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 条件付きUPDATEで使用済みにすることで、同時リクエストでも一度しか消費されない
+		result := tx.Model(&PasswordResetTokenModel{}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrTokenInvalid
+		}
+
+		var model PasswordResetTokenModel
+		if err := tx.First(&model, "token_hash = ?", tokenHash).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrTokenInvalid
+			}
+			return err
+		}
+		id, err := domain.NewID(model.UserID)
+		if err != nil {
+			return err
+		}
+		userID = id
+		return nil
+	})
+	if err != nil {
+		return domain.ID{}, err
+	}
+	return userID, nil
+}
+
+func (r *PasswordResetTokenRepository) DeleteByUserID(userID domain.ID) error {
+	return r.db.WithContext(r.ctx).Delete(&PasswordResetTokenModel{}, "user_id = ?", userID.String()).Error
+}
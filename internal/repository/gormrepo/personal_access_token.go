@@ -0,0 +1,154 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenModel struct {
+	ID         string     `gorm:"column:id;primaryKey;not null"`
+	UserID     string     `gorm:"column:user_id;not null;index"`
+	Name       string     `gorm:"column:name;not null"`
+	TokenHash  string     `gorm:"column:token_hash;not null;uniqueIndex;size:64"`
+	Scopes     string     `gorm:"column:scopes;not null"` // スペース区切り
+	ExpiresAt  *time.Time `gorm:"column:expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null"`
+}
+
+func (PersonalAccessTokenModel) TableName() string {
+	return "personal_access_tokens"
+}
+
+func toPersonalAccessTokenDomain(model PersonalAccessTokenModel) (domain.PersonalAccessToken, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.PersonalAccessToken{}, err
+	}
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.PersonalAccessToken{}, err
+	}
+	name, err := domain.NewTokenName(model.Name)
+	if err != nil {
+		return domain.PersonalAccessToken{}, err
+	}
+	scopes, err := domain.NewScopes(strings.Fields(model.Scopes))
+	if err != nil {
+		return domain.PersonalAccessToken{}, err
+	}
+	var expiresAt domain.ExpiresAt
+	if model.ExpiresAt != nil {
+		expiresAt = domain.NewExpiresAt(*model.ExpiresAt)
+	}
+	var lastUsedAt time.Time
+	if model.LastUsedAt != nil {
+		lastUsedAt = *model.LastUsedAt
+	}
+
+	return domain.NewPersonalAccessToken(
+		id,
+		userID,
+		name,
+		model.TokenHash,
+		scopes,
+		expiresAt,
+		lastUsedAt,
+		domain.NewCreatedAt(model.CreatedAt),
+	), nil
+}
+
+type PersonalAccessTokenRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB, ctx context.Context) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *PersonalAccessTokenRepository) FindByHash(tokenHash string) (domain.PersonalAccessToken, error) {
+	var model PersonalAccessTokenModel
+	if err := r.db.WithContext(r.ctx).
+		First(&model, "token_hash = ? AND revoked_at IS NULL", tokenHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.PersonalAccessToken{}, domain.ErrEntityNotFound
+		}
+		return domain.PersonalAccessToken{}, err
+	}
+	return toPersonalAccessTokenDomain(model)
+}
+
+func (r *PersonalAccessTokenRepository) FindByUserID(userID domain.ID) ([]domain.PersonalAccessToken, error) {
+	var models []PersonalAccessTokenModel
+	if err := r.db.WithContext(r.ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID.String()).
+		Order("created_at DESC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	tokens := make([]domain.PersonalAccessToken, len(models))
+	for i, model := range models {
+		token, err := toPersonalAccessTokenDomain(model)
+		if err != nil {
+			return nil, err
+		}
+		tokens[i] = token
+	}
+	return tokens, nil
+}
+
+func (r *PersonalAccessTokenRepository) Save(token domain.PersonalAccessToken) (domain.PersonalAccessToken, error) {
+	model := PersonalAccessTokenModel{
+		ID:        token.ID().String(),
+		UserID:    token.UserID().String(),
+		Name:      token.Name().Value(),
+		TokenHash: token.TokenHash(),
+		Scopes:    token.Scopes().String(),
+		CreatedAt: token.CreatedAt().Value(),
+	}
+	if !token.ExpiresAt().IsZero() {
+		expiresAt := token.ExpiresAt().Value()
+		model.ExpiresAt = &expiresAt
+	}
+	if !token.LastUsedAt().IsZero() {
+		lastUsedAt := token.LastUsedAt()
+		model.LastUsedAt = &lastUsedAt
+	}
+
+	if err := r.db.WithContext(r.ctx).Save(&model).Error; err != nil {
+		return domain.PersonalAccessToken{}, err
+	}
+	return toPersonalAccessTokenDomain(model)
+}
+
+func (r *PersonalAccessTokenRepository) Revoke(id domain.ID, userID domain.ID) error {
+	result := r.db.WithContext(r.ctx).
+		Model(&PersonalAccessTokenModel{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id.String(), userID.String()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrEntityNotFound
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) TouchLastUsed(id domain.ID, usedAt time.Time) error {
+	return r.db.WithContext(r.ctx).
+		Model(&PersonalAccessTokenModel{}).
+		Where("id = ?", id.String()).
+		Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,83 @@
+package gormrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeModel struct {
+	ID        string     `gorm:"column:id;primaryKey;not null"`
+	UserID    string     `gorm:"column:user_id;not null;index"`
+	CodeHash  string     `gorm:"column:code_hash;not null;size:64"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null"`
+}
+
+func (RecoveryCodeModel) TableName() string {
+	return "recovery_codes"
+}
+
+type RecoveryCodeRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB, ctx context.Context) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *RecoveryCodeRepository) Replace(userID domain.ID, codes []domain.RecoveryCode) error {
+	return r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&RecoveryCodeModel{}, "user_id = ?", userID.String()).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		models := make([]RecoveryCodeModel, len(codes))
+		for i, code := range codes {
+			models[i] = RecoveryCodeModel{
+				ID:       code.ID().String(),
+				UserID:   userID.String(),
+				CodeHash: code.CodeHash(),
+			}
+		}
+		return tx.Create(&models).Error
+	})
+}
+
+func (r *RecoveryCodeRepository) Consume(userID domain.ID, codeHash string) error {
+	// 条件付きUPDATEで使用済みにすることで、同時リクエストでも一度しか消費されない
+	result := r.db.WithContext(r.ctx).
+		Model(&RecoveryCodeModel{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID.String(), codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrTokenInvalid
+	}
+	return nil
+}
+
+func (r *RecoveryCodeRepository) CountUnused(userID domain.ID) (int, error) {
+	var count int64
+	if err := r.db.WithContext(r.ctx).
+		Model(&RecoveryCodeModel{}).
+		Where("user_id = ? AND used_at IS NULL", userID.String()).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (r *RecoveryCodeRepository) DeleteByUserID(userID domain.ID) error {
+	return r.db.WithContext(r.ctx).Delete(&RecoveryCodeModel{}, "user_id = ?", userID.String()).Error
+}
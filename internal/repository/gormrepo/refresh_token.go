@@ -0,0 +1,139 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefreshTokenModel struct {
+	ID         string     `gorm:"column:id;primaryKey;not null"`
+	UserID     string     `gorm:"column:user_id;not null;index"`
+	FamilyID   string     `gorm:"column:family_id;not null;index"`
+	TokenHash  string     `gorm:"column:token_hash;not null;uniqueIndex;size:64"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at;not null"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+	ReplacedBy *string    `gorm:"column:replaced_by"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null"`
+}
+
+func (RefreshTokenModel) TableName() string {
+	return "refresh_tokens"
+}
+
+func toRefreshTokenDomain(model RefreshTokenModel) (domain.RefreshToken, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	familyID, err := domain.NewID(model.FamilyID)
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	return domain.NewRefreshToken(
+		id,
+		userID,
+		familyID,
+		model.TokenHash,
+		domain.NewExpiresAt(model.ExpiresAt),
+		model.RevokedAt != nil,
+	), nil
+}
+
+type RefreshTokenRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewRefreshTokenRepository(db *gorm.DB, ctx context.Context) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *RefreshTokenRepository) FindByHash(tokenHash string) (domain.RefreshToken, error) {
+	var model RefreshTokenModel
+	if err := r.db.WithContext(r.ctx).First(&model, "token_hash = ?", tokenHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.RefreshToken{}, domain.ErrEntityNotFound
+		}
+		return domain.RefreshToken{}, err
+	}
+	return toRefreshTokenDomain(model)
+}
+
+func (r *RefreshTokenRepository) Save(token domain.RefreshToken) (domain.RefreshToken, error) {
+	model := RefreshTokenModel{
+		ID:        token.ID().String(),
+		UserID:    token.UserID().String(),
+		FamilyID:  token.FamilyID().String(),
+		TokenHash: token.TokenHash(),
+		ExpiresAt: token.ExpiresAt().Value(),
+	}
+	if token.Revoked() {
+		now := time.Now()
+		model.RevokedAt = &now
+	}
+	if err := r.db.WithContext(r.ctx).Create(&model).Error; err != nil {
+		return domain.RefreshToken{}, err
+	}
+	return toRefreshTokenDomain(model)
+}
+
+func (r *RefreshTokenRepository) Rotate(current domain.RefreshToken, next domain.RefreshToken) (domain.RefreshToken, error) {
+	var saved domain.RefreshToken
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var model RefreshTokenModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&model, "id = ?", current.ID().String()).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrEntityNotFound
+			}
+			return err
+		}
+		// 行ロック取得までの間に他のリクエストがローテーション済みであれば再利用とみなす
+		if model.RevokedAt != nil {
+			return domain.ErrTokenInvalid
+		}
+
+		nextID := next.ID().String()
+		now := time.Now()
+		if err := tx.Model(&model).Updates(map[string]any{
+			"revoked_at":  now,
+			"replaced_by": nextID,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		saved, err = NewRefreshTokenRepository(tx, r.ctx).Save(next)
+		return err
+	})
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	return saved, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID domain.ID) error {
+	return r.db.WithContext(r.ctx).
+		Model(&RefreshTokenModel{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID.String()).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(userID domain.ID) error {
+	return r.db.WithContext(r.ctx).
+		Model(&RefreshTokenModel{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID.String()).
+		Update("revoked_at", time.Now()).Error
+}
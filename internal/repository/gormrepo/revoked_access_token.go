@@ -0,0 +1,55 @@
+package gormrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedAccessTokenModel struct {
+	JTI       string    `gorm:"column:jti;primaryKey;not null;size:64"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+func (RevokedAccessTokenModel) TableName() string {
+	return "revoked_access_tokens"
+}
+
+type RevokedAccessTokenRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewRevokedAccessTokenRepository(db *gorm.DB, ctx context.Context) *RevokedAccessTokenRepository {
+	return &RevokedAccessTokenRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *RevokedAccessTokenRepository) Revoke(jti string, expiresAt domain.ExpiresAt) error {
+	model := RevokedAccessTokenModel{
+		JTI:       jti,
+		ExpiresAt: expiresAt.Value(),
+	}
+	return r.db.WithContext(r.ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model).Error
+}
+
+func (r *RevokedAccessTokenRepository) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(r.ctx).
+		Model(&RevokedAccessTokenModel{}).
+		Where("jti = ?", jti).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *RevokedAccessTokenRepository) DeleteExpired() error {
+	return r.db.WithContext(r.ctx).Delete(&RevokedAccessTokenModel{}, "expires_at <= ?", time.Now()).Error
+}
@@ -0,0 +1,201 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type ShareLinkModel struct {
+	ID             string     `gorm:"column:id;primaryKey;not null"`
+	DocID          string     `gorm:"column:doc_id;not null;index"`
+	TokenHash      string     `gorm:"column:token_hash;not null;uniqueIndex;size:64"`
+	PasswordHash   string     `gorm:"column:password_hash;not null;default:''"`
+	ExpiresAt      *time.Time `gorm:"column:expires_at"`
+	CreatedBy      string     `gorm:"column:created_by;not null"`
+	AccessCount    int        `gorm:"column:access_count;not null;default:0"`
+	LastAccessedAt *time.Time `gorm:"column:last_accessed_at"`
+	FailedAttempts int        `gorm:"column:failed_attempts;not null;default:0"`
+	LastFailedAt   *time.Time `gorm:"column:last_failed_at"`
+	RevokedAt      *time.Time `gorm:"column:revoked_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null"`
+}
+
+func (ShareLinkModel) TableName() string {
+	return "share_links"
+}
+
+func toShareLinkDomain(model ShareLinkModel) (domain.ShareLink, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.ShareLink{}, err
+	}
+	docID, err := domain.NewID(model.DocID)
+	if err != nil {
+		return domain.ShareLink{}, err
+	}
+	createdBy, err := domain.NewID(model.CreatedBy)
+	if err != nil {
+		return domain.ShareLink{}, err
+	}
+	var expiresAt domain.ExpiresAt
+	if model.ExpiresAt != nil {
+		expiresAt = domain.NewExpiresAt(*model.ExpiresAt)
+	}
+	var lastAccessedAt time.Time
+	if model.LastAccessedAt != nil {
+		lastAccessedAt = *model.LastAccessedAt
+	}
+	var lastFailedAt time.Time
+	if model.LastFailedAt != nil {
+		lastFailedAt = *model.LastFailedAt
+	}
+
+	return domain.NewShareLink(
+		id,
+		docID,
+		model.TokenHash,
+		domain.NewPasswordHash(model.PasswordHash),
+		expiresAt,
+		createdBy,
+		model.AccessCount,
+		lastAccessedAt,
+		model.FailedAttempts,
+		lastFailedAt,
+		domain.NewCreatedAt(model.CreatedAt),
+	), nil
+}
+
+type ShareLinkRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewShareLinkRepository(db *gorm.DB, ctx context.Context) *ShareLinkRepository {
+	return &ShareLinkRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *ShareLinkRepository) FindByHash(tokenHash string) (domain.ShareLink, error) {
+	var model ShareLinkModel
+	if err := r.db.WithContext(r.ctx).
+		First(&model, "token_hash = ? AND revoked_at IS NULL", tokenHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ShareLink{}, domain.ErrEntityNotFound
+		}
+		return domain.ShareLink{}, err
+	}
+	return toShareLinkDomain(model)
+}
+
+func (r *ShareLinkRepository) FindByDocID(docID domain.ID) ([]domain.ShareLink, error) {
+	var models []ShareLinkModel
+	if err := r.db.WithContext(r.ctx).
+		Where("doc_id = ? AND revoked_at IS NULL", docID.String()).
+		Order("created_at DESC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	links := make([]domain.ShareLink, len(models))
+	for i, model := range models {
+		link, err := toShareLinkDomain(model)
+		if err != nil {
+			return nil, err
+		}
+		links[i] = link
+	}
+	return links, nil
+}
+
+func (r *ShareLinkRepository) Save(link domain.ShareLink) (domain.ShareLink, error) {
+	model := ShareLinkModel{
+		ID:             link.ID().String(),
+		DocID:          link.DocID().String(),
+		TokenHash:      link.TokenHash(),
+		PasswordHash:   link.PasswordHash().Value(),
+		CreatedBy:      link.CreatedBy().String(),
+		AccessCount:    link.AccessCount(),
+		FailedAttempts: link.FailedAttempts(),
+		CreatedAt:      link.CreatedAt().Value(),
+	}
+	if !link.ExpiresAt().IsZero() {
+		expiresAt := link.ExpiresAt().Value()
+		model.ExpiresAt = &expiresAt
+	}
+	if !link.LastAccessedAt().IsZero() {
+		lastAccessedAt := link.LastAccessedAt()
+		model.LastAccessedAt = &lastAccessedAt
+	}
+	if !link.LastFailedAt().IsZero() {
+		lastFailedAt := link.LastFailedAt()
+		model.LastFailedAt = &lastFailedAt
+	}
+
+	if err := r.db.WithContext(r.ctx).Save(&model).Error; err != nil {
+		return domain.ShareLink{}, err
+	}
+	return toShareLinkDomain(model)
+}
+
+func (r *ShareLinkRepository) Revoke(id domain.ID, docID domain.ID) error {
+	result := r.db.WithContext(r.ctx).
+		Model(&ShareLinkModel{}).
+		Where("id = ? AND doc_id = ? AND revoked_at IS NULL", id.String(), docID.String()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrEntityNotFound
+	}
+	return nil
+}
+
+func (r *ShareLinkRepository) RecordAccess(id domain.ID) error {
+	return r.db.WithContext(r.ctx).
+		Model(&ShareLinkModel{}).
+		Where("id = ?", id.String()).
+		Updates(map[string]any{
+			"access_count":     gorm.Expr("access_count + 1"),
+			"last_accessed_at": time.Now(),
+		}).Error
+}
+
+func (r *ShareLinkRepository) RecordFailure(id domain.ID, window time.Duration) (int, error) {
+	var attempts int
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// MySQLはSETを左から順に評価するため、failed_attemptsの計算には更新前のlast_failed_atを使う
+		if err := tx.Model(&ShareLinkModel{}).
+			Where("id = ?", id.String()).
+			Updates(map[string]any{
+				"failed_attempts": gorm.Expr("CASE WHEN last_failed_at IS NULL OR last_failed_at <= ? THEN 1 ELSE failed_attempts + 1 END", now.Add(-window)),
+				"last_failed_at":  now,
+			}).Error; err != nil {
+			return err
+		}
+		var model ShareLinkModel
+		if err := tx.First(&model, "id = ?", id.String()).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrEntityNotFound
+			}
+			return err
+		}
+		attempts = model.FailedAttempts
+		return nil
+	})
+	return attempts, err
+}
+
+func (r *ShareLinkRepository) ResetFailures(id domain.ID) error {
+	return r.db.WithContext(r.ctx).
+		Model(&ShareLinkModel{}).
+		Where("id = ? AND failed_attempts > 0", id.String()).
+		Updates(map[string]any{"failed_attempts": 0, "last_failed_at": nil}).Error
+}
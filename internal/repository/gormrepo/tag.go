@@ -0,0 +1,282 @@
+package gormrepo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// タグ名は大文字・小文字を区別するため、バイナリ照合順序で比較する
+type TagModel struct {
+	ID   uint   `gorm:"column:id;primaryKey;autoIncrement"`
+	Name string `gorm:"column:name;type:varchar(50) COLLATE utf8mb4_bin;not null;uniqueIndex"`
+}
+
+func (TagModel) TableName() string {
+	return "tags"
+}
+
+type DocTagModel struct {
+	DocID string `gorm:"column:doc_id;primaryKey;size:36"`
+	TagID uint   `gorm:"column:tag_id;primaryKey;index"`
+	// ドキュメントに指定された順序
+	Position int `gorm:"column:position;not null"`
+}
+
+func (DocTagModel) TableName() string {
+	return "doc_tags"
+}
+
+// ドキュメントのタグを置き換える。存在しないタグは作成する
+func replaceDocTags(tx *gorm.DB, docID string, names []string) error {
+	if err := tx.Delete(&DocTagModel{}, "doc_id = ?", docID).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	tagIDs, err := ensureTags(tx, names)
+	if err != nil {
+		return err
+	}
+	docTags := make([]DocTagModel, len(names))
+	for i, name := range names {
+		docTags[i] = DocTagModel{DocID: docID, TagID: tagIDs[name], Position: i}
+	}
+	return tx.Create(&docTags).Error
+}
+
+// タグを作成し、タグ名からIDへの対応を返す。作成済みのタグはそのまま使う
+func ensureTags(tx *gorm.DB, names []string) (map[string]uint, error) {
+	models := make([]TagModel, len(names))
+	for i, name := range names {
+		models[i] = TagModel{Name: name}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models).Error; err != nil {
+		return nil, err
+	}
+
+	var tags []TagModel
+	if err := tx.Where("name IN ?", names).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uint, len(tags))
+	for _, tag := range tags {
+		ids[tag.Name] = tag.ID
+	}
+	return ids, nil
+}
+
+// ドキュメントIDからタグ名の一覧への対応を返す
+func findDocTagNames(db *gorm.DB, docIDs []string) (map[string][]string, error) {
+	names := make(map[string][]string, len(docIDs))
+	if len(docIDs) == 0 {
+		return names, nil
+	}
+
+	var rows []struct {
+		DocID string
+		Name  string
+	}
+	if err := db.Model(&DocTagModel{}).
+		Select("doc_tags.doc_id, tags.name").
+		Joins("JOIN tags ON tags.id = doc_tags.tag_id").
+		Where("doc_tags.doc_id IN ?", docIDs).
+		Order("doc_tags.doc_id, doc_tags.position").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		names[row.DocID] = append(names[row.DocID], row.Name)
+	}
+	return names, nil
+}
+
+type TagRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewTagRepository(db *gorm.DB, ctx context.Context) *TagRepository {
+	return &TagRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+var _ domain.TagRepository = (*TagRepository)(nil)
+
+func (r *TagRepository) FindCounts(workspaceID domain.ID, prefix string, limit int) ([]domain.TagCount, error) {
+	queryDB := r.db.WithContext(r.ctx).Model(&DocTagModel{}).
+		Select("tags.name, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = doc_tags.tag_id").
+		Joins("JOIN docs ON docs.id = doc_tags.doc_id").
+		Where("docs.workspace_id = ? AND docs.deleted_at IS NULL", workspaceID.String())
+	if prefix != "" {
+		queryDB = queryDB.Where("LOWER(tags.name) LIKE ?", escapeLike(strings.ToLower(prefix))+"%")
+	}
+
+	var rows []struct {
+		Name  string
+		Count int
+	}
+	if err := queryDB.Group("tags.id, tags.name").
+		Order("count DESC, tags.name ASC").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make([]domain.TagCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.NewTagCount(row.Name, row.Count)
+	}
+	return counts, nil
+}
+
+func (r *TagRepository) Merge(workspaceID domain.ID, sources []string, target string, editorID domain.ID) ([]domain.Doc, error) {
+	isSource := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		isSource[source] = true
+	}
+	return r.updateDocTags(workspaceID, sources, editorID, func(tags []string) []string {
+		updated := make([]string, 0, len(tags))
+		seen := make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			if isSource[tag] {
+				tag = target
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				updated = append(updated, tag)
+			}
+		}
+		return updated
+	})
+}
+
+func (r *TagRepository) Delete(workspaceID domain.ID, name string, editorID domain.ID) ([]domain.Doc, error) {
+	return r.updateDocTags(workspaceID, []string{name}, editorID, func(tags []string) []string {
+		updated := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if tag != name {
+				updated = append(updated, tag)
+			}
+		}
+		return updated
+	})
+}
+
+// namesのいずれかのタグが付いたドキュメントのタグをupdateで変更し、1つのトランザクションで保存する
+func (r *TagRepository) updateDocTags(workspaceID domain.ID, names []string, editorID domain.ID, update func(tags []string) []string) ([]domain.Doc, error) {
+	var saved []domain.Doc
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var docIDs []string
+		if err := tx.Model(&DocModel{}).
+			Distinct("docs.id").
+			Joins("JOIN doc_tags ON doc_tags.doc_id = docs.id").
+			Joins("JOIN tags ON tags.id = doc_tags.tag_id").
+			Where("docs.workspace_id = ? AND tags.name IN ?", workspaceID.String(), names).
+			Order("docs.id").
+			Pluck("docs.id", &docIDs).Error; err != nil {
+			return err
+		}
+		if len(docIDs) == 0 {
+			return domain.ErrEntityNotFound
+		}
+
+		docRepo := NewDocRepository(tx, r.ctx)
+		for _, docID := range docIDs {
+			id, err := domain.NewID(docID)
+			if err != nil {
+				return err
+			}
+			doc, err := docRepo.Find(id)
+			if err != nil {
+				return err
+			}
+			tags, err := domain.NewTagList(update(doc.Tags().Values()))
+			if err != nil {
+				return err
+			}
+			updated, err := docRepo.Save(domain.NewDoc(
+				doc.ID(),
+				doc.WorkspaceID(),
+				doc.Title(),
+				doc.Content(),
+				tags,
+				doc.Snippet(),
+				doc.AuthorId(),
+				doc.CreatedAt(),
+				domain.NewEditedAtNow(),
+				editorID,
+				doc.Version(),
+				doc.ParentID(),
+				doc.Position(),
+			))
+			if err != nil {
+				return err
+			}
+			saved = append(saved, updated)
+		}
+		return deleteUnusedTags(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// どのドキュメントにも付いていないタグを削除する
+func deleteUnusedTags(tx *gorm.DB) error {
+	return tx.Exec("DELETE FROM tags WHERE NOT EXISTS (SELECT 1 FROM doc_tags WHERE doc_tags.tag_id = tags.id)").Error
+}
+
+// LIKEのワイルドカードとして解釈される文字をエスケープする
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// MigrateCSVTagsはdocsテーブルのカンマ区切りのtagsカラムをtags・doc_tagsテーブルに移し、カラムを削除する
+// 移行済みの場合は何もしない。ゴミ箱のドキュメントも対象にする
+func (r *TagRepository) MigrateCSVTags() error {
+	db := r.db.WithContext(r.ctx)
+	if !db.Migrator().HasColumn("docs", "tags") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID   string
+			Tags string
+		}
+		if err := tx.Table("docs").Select("id, tags").Where("tags <> ''").Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := replaceDocTags(tx, row.ID, splitCSVTags(row.Tags)); err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropColumn("docs", "tags")
+	})
+}
+
+// 移行前のデータは検証されていない場合があるため、空のタグや重複を除いて読み込む
+func splitCSVTags(csv string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(csv, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" || seen[name] || len([]rune(name)) > 50 {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,131 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+type TOTPCredentialModel struct {
+	UserID       string `gorm:"column:user_id;primaryKey;not null"`
+	Secret       string `gorm:"column:secret;not null"`
+	Enabled      bool   `gorm:"column:enabled;not null;default:false"`
+	LastUsedStep int64  `gorm:"column:last_used_step;not null;default:0"`
+	// 2要素目の検証に続けて失敗した回数（ログイン・ログイン後の操作の合計）
+	FailedAttempts int        `gorm:"column:failed_attempts;not null;default:0"`
+	LastFailedAt   *time.Time `gorm:"column:last_failed_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;not null"`
+}
+
+func (TOTPCredentialModel) TableName() string {
+	return "totp_credentials"
+}
+
+func toTOTPCredentialDomain(model TOTPCredentialModel) (domain.TOTPCredential, error) {
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.TOTPCredential{}, err
+	}
+	var lastFailedAt time.Time
+	if model.LastFailedAt != nil {
+		lastFailedAt = *model.LastFailedAt
+	}
+	return domain.NewTOTPCredential(userID, model.Secret, model.Enabled, model.LastUsedStep, model.FailedAttempts, lastFailedAt), nil
+}
+
+type TOTPCredentialRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewTOTPCredentialRepository(db *gorm.DB, ctx context.Context) *TOTPCredentialRepository {
+	return &TOTPCredentialRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *TOTPCredentialRepository) Find(userID domain.ID) (domain.TOTPCredential, error) {
+	var model TOTPCredentialModel
+	if err := r.db.WithContext(r.ctx).First(&model, "user_id = ?", userID.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.TOTPCredential{}, domain.ErrEntityNotFound
+		}
+		return domain.TOTPCredential{}, err
+	}
+	return toTOTPCredentialDomain(model)
+}
+
+func (r *TOTPCredentialRepository) Save(credential domain.TOTPCredential) (domain.TOTPCredential, error) {
+	model := TOTPCredentialModel{
+		UserID:         credential.UserID().String(),
+		Secret:         credential.Secret(),
+		Enabled:        credential.Enabled(),
+		LastUsedStep:   credential.LastUsedStep(),
+		FailedAttempts: credential.FailedAttempts(),
+	}
+	if !credential.LastFailedAt().IsZero() {
+		lastFailedAt := credential.LastFailedAt()
+		model.LastFailedAt = &lastFailedAt
+	}
+	if err := r.db.WithContext(r.ctx).Save(&model).Error; err != nil {
+		return domain.TOTPCredential{}, err
+	}
+	return toTOTPCredentialDomain(model)
+}
+
+func (r *TOTPCredentialRepository) MarkStepUsed(userID domain.ID, step int64) error {
+	// 条件付きUPDATEにすることで、同じコードを同時に送信されても一度しか受け付けない
+	result := r.db.WithContext(r.ctx).
+		Model(&TOTPCredentialModel{}).
+		Where("user_id = ? AND last_used_step < ?", userID.String(), step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrTokenInvalid
+	}
+	return nil
+}
+
+func (r *TOTPCredentialRepository) RecordFailure(userID domain.ID, window time.Duration) (int, error) {
+	var attempts int
+	err := r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// MySQLはSETを左から順に評価するため、failed_attemptsの計算には更新前のlast_failed_atを使う
+		if err := tx.Model(&TOTPCredentialModel{}).
+			Where("user_id = ?", userID.String()).
+			Updates(map[string]any{
+				"failed_attempts": gorm.Expr("CASE WHEN last_failed_at IS NULL OR last_failed_at <= ? THEN 1 ELSE failed_attempts + 1 END", now.Add(-window)),
+				"last_failed_at":  now,
+			}).Error; err != nil {
+			return err
+		}
+		var model TOTPCredentialModel
+		if err := tx.First(&model, "user_id = ?", userID.String()).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrEntityNotFound
+			}
+			return err
+		}
+		attempts = model.FailedAttempts
+		return nil
+	})
+	return attempts, err
+}
+
+func (r *TOTPCredentialRepository) ResetFailures(userID domain.ID) error {
+	return r.db.WithContext(r.ctx).
+		Model(&TOTPCredentialModel{}).
+		Where("user_id = ? AND failed_attempts > 0", userID.String()).
+		Updates(map[string]any{"failed_attempts": 0, "last_failed_at": nil}).Error
+}
+
+func (r *TOTPCredentialRepository) Delete(userID domain.ID) error {
+	return r.db.WithContext(r.ctx).Delete(&TOTPCredentialModel{}, "user_id = ?", userID.String()).Error
+}
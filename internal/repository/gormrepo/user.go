@@ -4,16 +4,22 @@ import (
 	"context"
 	"errors"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/password"
 	"gorm.io/gorm"
 )
 
 type UserModel struct {
 	gorm.Model
-	ID         string `gorm:"column:id;primaryKey;not null"`
-	Email      string `gorm:"column:email;not null;unique"`
-	AuthorName string `gorm:"column:author_name;not null"`
-	UITheme    string `gorm:"column:ui_theme;not null"`
+	ID            string `gorm:"column:id;primaryKey;not null"`
+	Email         string `gorm:"column:email;not null;unique"`
+	AuthorName    string `gorm:"column:author_name;not null"`
+	UITheme       string `gorm:"column:ui_theme;not null"`
+	PasswordHash  string `gorm:"column:password_hash;not null;default:''"`
+	EmailVerified bool   `gorm:"column:email_verified;not null;default:false"`
+	// 未選択の場合は空文字
+	ActiveWorkspaceID string `gorm:"column:active_workspace_id;not null;default:''"`
 }
 
 func (UserModel) TableName() string {
@@ -38,7 +44,15 @@ func toUserDomain(model UserModel) (domain.User, error) {
 		return domain.User{}, err
 	}
 
-	return domain.NewUser(id, email, authorName, uiTheme), nil
+	passwordHash := domain.NewPasswordHash(model.PasswordHash)
+	var activeWorkspaceID domain.ID
+	if model.ActiveWorkspaceID != "" {
+		if activeWorkspaceID, err = domain.NewID(model.ActiveWorkspaceID); err != nil {
+			return domain.User{}, err
+		}
+	}
+
+	return domain.NewUser(id, email, authorName, uiTheme, passwordHash, model.EmailVerified, activeWorkspaceID), nil
 }
 
 type UserRepository struct {
@@ -67,6 +81,17 @@ func (r *UserRepository) Find(id domain.ID) (domain.User, error) {
 	return toUserDomain(model)
 }
 
+func (r *UserRepository) FindByEmail(email domain.Email) (domain.User, error) {
+	var model UserModel
+	if err := r.db.WithContext(r.ctx).First(&model, "email = ?", email.Value()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, domain.ErrEntityNotFound
+		}
+		return domain.User{}, err
+	}
+	return toUserDomain(model)
+}
+
 func (r *UserRepository) Save(user domain.User) (domain.User, error) {
 	var existing UserModel
 	err := r.db.WithContext(r.ctx).First(&existing, "id = ?", user.ID().String()).Error
@@ -75,10 +100,15 @@ func (r *UserRepository) Save(user domain.User) (domain.User, error) {
 	}
 
 	model := UserModel{
-		ID:         user.ID().String(),
-		Email:      user.Email().Value(),
-		AuthorName: user.AuthorName().Value(),
-		UITheme:    user.UITheme().Value(),
+		ID:            user.ID().String(),
+		Email:         user.Email().Value(),
+		AuthorName:    user.AuthorName().Value(),
+		UITheme:       user.UITheme().Value(),
+		PasswordHash:  user.PasswordHash().Value(),
+		EmailVerified: user.EmailVerified(),
+	}
+	if !user.ActiveWorkspaceID().IsNil() {
+		model.ActiveWorkspaceID = user.ActiveWorkspaceID().String()
 	}
 	if err == nil {
 		model.CreatedAt = existing.CreatedAt
@@ -93,12 +123,23 @@ func (r *UserRepository) Save(user domain.User) (domain.User, error) {
 		if errors.Is(err, gorm.ErrInvalidData) {
 			return domain.User{}, domain.ErrValidationFailed
 		}
+		// 同時に同じメールアドレスで登録された場合
+		if isDuplicateKey(err) {
+			return domain.User{}, domain.ErrEntityAlreadyExists
+		}
 		return domain.User{}, err
 	}
 
 	return toUserDomain(model)
 }
 
+// 一意制約に違反したエラーかを判定する
+func isDuplicateKey(err error) bool {
+	const erDupEntry = 1062
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == erDupEntry
+}
+
 func (r *UserRepository) Delete(id domain.ID) error {
 	if err := r.db.WithContext(r.ctx).Delete(&UserModel{}, "id = ?", id.Value()).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -109,7 +150,7 @@ func (r *UserRepository) Delete(id domain.ID) error {
 	return nil
 }
 
-func (r *UserRepository) SeedDummyUser() error {
+func (r *UserRepository) SeedDummyUser(hasher *password.Hasher) error {
 	id, err := domain.NewID("123e4567-e89b-12d3-a456-426614174000")
 	if err != nil {
 		return err
@@ -128,7 +169,11 @@ func (r *UserRepository) SeedDummyUser() error {
 		return err
 	}
 	uiTheme := domain.DefaultUITheme()
-	dummyUser := domain.NewUser(id, email, authorName, uiTheme)
+	hash, err := hasher.Hash("password123")
+	if err != nil {
+		return err
+	}
+	dummyUser := domain.NewUser(id, email, authorName, uiTheme, domain.NewPasswordHash(hash), true, domain.ID{})
 	_, err = r.Save(dummyUser)
 	return err
 }
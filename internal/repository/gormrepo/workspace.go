@@ -0,0 +1,177 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+)
+
+// 個人ワークスペースの既定の名前
+const PersonalWorkspaceName = "Personal"
+
+type WorkspaceModel struct {
+	ID   string `gorm:"column:id;primaryKey;not null;size:36"`
+	Name string `gorm:"column:name;not null"`
+	// 個人ワークスペースの持ち主。ユーザーごとに1つに制限するためユニークにする
+	PersonalOwnerID *string   `gorm:"column:personal_owner_id;size:36;uniqueIndex"`
+	CreatedAt       time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;not null"`
+}
+
+func (WorkspaceModel) TableName() string {
+	return "workspaces"
+}
+
+func toWorkspaceDomain(model WorkspaceModel) (domain.Workspace, error) {
+	id, err := domain.NewID(model.ID)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	name, err := domain.NewWorkspaceName(model.Name)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	var personalOwnerID domain.ID
+	if model.PersonalOwnerID != nil {
+		if personalOwnerID, err = domain.NewID(*model.PersonalOwnerID); err != nil {
+			return domain.Workspace{}, err
+		}
+	}
+	return domain.NewWorkspace(id, name, personalOwnerID, domain.NewCreatedAt(model.CreatedAt)), nil
+}
+
+type WorkspaceRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewWorkspaceRepository(db *gorm.DB, ctx context.Context) *WorkspaceRepository {
+	return &WorkspaceRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *WorkspaceRepository) Find(id domain.ID) (domain.Workspace, error) {
+	var model WorkspaceModel
+	if err := r.db.WithContext(r.ctx).First(&model, "id = ?", id.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Workspace{}, domain.ErrEntityNotFound
+		}
+		return domain.Workspace{}, err
+	}
+	return toWorkspaceDomain(model)
+}
+
+func (r *WorkspaceRepository) FindPersonal(userID domain.ID) (domain.Workspace, error) {
+	var model WorkspaceModel
+	if err := r.db.WithContext(r.ctx).First(&model, "personal_owner_id = ?", userID.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Workspace{}, domain.ErrEntityNotFound
+		}
+		return domain.Workspace{}, err
+	}
+	return toWorkspaceDomain(model)
+}
+
+func (r *WorkspaceRepository) FindByUserID(userID domain.ID) ([]domain.Workspace, error) {
+	var models []WorkspaceModel
+	// 個人ワークスペースを先頭に、参加順で返す
+	if err := r.db.WithContext(r.ctx).
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ?", userID.String()).
+		Order("workspaces.personal_owner_id IS NULL, workspace_members.created_at ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	workspaces := make([]domain.Workspace, len(models))
+	for i, model := range models {
+		workspace, err := toWorkspaceDomain(model)
+		if err != nil {
+			return nil, err
+		}
+		workspaces[i] = workspace
+	}
+	return workspaces, nil
+}
+
+func (r *WorkspaceRepository) Save(workspace domain.Workspace) (domain.Workspace, error) {
+	model := WorkspaceModel{
+		ID:        workspace.ID().String(),
+		Name:      workspace.Name().Value(),
+		CreatedAt: workspace.CreatedAt().Value(),
+	}
+	if workspace.IsPersonal() {
+		personalOwnerID := workspace.PersonalOwnerID().String()
+		model.PersonalOwnerID = &personalOwnerID
+	}
+	if err := r.db.WithContext(r.ctx).Save(&model).Error; err != nil {
+		return domain.Workspace{}, err
+	}
+	return toWorkspaceDomain(model)
+}
+
+// BackfillPersonalWorkspacesはワークスペース導入前のデータを移行する
+// 個人ワークスペースのないユーザーに作成し、未割り当てのドキュメントを作成者の個人ワークスペースへ移す
+// 何度実行しても結果は変わらないため、起動時に毎回実行してよい
+func (r *WorkspaceRepository) BackfillPersonalWorkspaces() error {
+	return r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		var userIDs []string
+		if err := tx.Model(&UserModel{}).
+			Where("id NOT IN (?)", tx.Model(&WorkspaceModel{}).Select("personal_owner_id").Where("personal_owner_id IS NOT NULL")).
+			Pluck("id", &userIDs).Error; err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			id, err := domain.NewID(userID)
+			if err != nil {
+				return err
+			}
+			if _, err := CreatePersonalWorkspace(tx, r.ctx, id); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec(
+			"UPDATE users JOIN workspaces ON workspaces.personal_owner_id = users.id " +
+				"SET users.active_workspace_id = workspaces.id WHERE users.active_workspace_id = ''",
+		).Error; err != nil {
+			return err
+		}
+		return tx.Exec(
+			"UPDATE docs JOIN workspaces ON workspaces.personal_owner_id = docs.author_id " +
+				"SET docs.workspace_id = workspaces.id WHERE docs.workspace_id = ''",
+		).Error
+	})
+}
+
+// CreatePersonalWorkspaceはユーザーの個人ワークスペースを作成し、ownerとして登録する
+func CreatePersonalWorkspace(db *gorm.DB, ctx context.Context, userID domain.ID) (domain.Workspace, error) {
+	name, err := domain.NewWorkspaceName(PersonalWorkspaceName)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	workspace, err := NewWorkspaceRepository(db, ctx).Save(domain.NewWorkspace(
+		domain.GenerateID(),
+		name,
+		userID,
+		domain.NewCreatedAtNow(),
+	))
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	_, err = NewWorkspaceMemberRepository(db, ctx).Save(domain.NewWorkspaceMember(
+		workspace.ID(),
+		userID,
+		domain.OwnerWorkspaceRole(),
+		domain.NewCreatedAtNow(),
+	))
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	return workspace, nil
+}
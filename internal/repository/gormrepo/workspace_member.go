@@ -0,0 +1,122 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WorkspaceMemberModel struct {
+	WorkspaceID string    `gorm:"column:workspace_id;primaryKey;not null;size:36"`
+	UserID      string    `gorm:"column:user_id;primaryKey;not null;size:36;index"`
+	Role        string    `gorm:"column:role;not null;size:16"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;not null"`
+}
+
+func (WorkspaceMemberModel) TableName() string {
+	return "workspace_members"
+}
+
+func toWorkspaceMemberDomain(model WorkspaceMemberModel) (domain.WorkspaceMember, error) {
+	workspaceID, err := domain.NewID(model.WorkspaceID)
+	if err != nil {
+		return domain.WorkspaceMember{}, err
+	}
+	userID, err := domain.NewID(model.UserID)
+	if err != nil {
+		return domain.WorkspaceMember{}, err
+	}
+	role, err := domain.NewWorkspaceRole(model.Role)
+	if err != nil {
+		return domain.WorkspaceMember{}, err
+	}
+	return domain.NewWorkspaceMember(workspaceID, userID, role, domain.NewCreatedAt(model.CreatedAt)), nil
+}
+
+type WorkspaceMemberRepository struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+func NewWorkspaceMemberRepository(db *gorm.DB, ctx context.Context) *WorkspaceMemberRepository {
+	return &WorkspaceMemberRepository{
+		db:  db,
+		ctx: ctx,
+	}
+}
+
+func (r *WorkspaceMemberRepository) Find(workspaceID domain.ID, userID domain.ID) (domain.WorkspaceMember, error) {
+	var model WorkspaceMemberModel
+	if err := r.db.WithContext(r.ctx).
+		First(&model, "workspace_id = ? AND user_id = ?", workspaceID.String(), userID.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.WorkspaceMember{}, domain.ErrEntityNotFound
+		}
+		return domain.WorkspaceMember{}, err
+	}
+	return toWorkspaceMemberDomain(model)
+}
+
+func (r *WorkspaceMemberRepository) FindByWorkspaceID(workspaceID domain.ID) ([]domain.WorkspaceMember, error) {
+	var models []WorkspaceMemberModel
+	if err := r.db.WithContext(r.ctx).
+		Where("workspace_id = ?", workspaceID.String()).
+		Order("created_at ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	members := make([]domain.WorkspaceMember, len(models))
+	for i, model := range models {
+		member, err := toWorkspaceMemberDomain(model)
+		if err != nil {
+			return nil, err
+		}
+		members[i] = member
+	}
+	return members, nil
+}
+
+func (r *WorkspaceMemberRepository) CountOwners(workspaceID domain.ID) (int, error) {
+	var count int64
+	if err := r.db.WithContext(r.ctx).
+		Model(&WorkspaceMemberModel{}).
+		Where("workspace_id = ? AND role = ?", workspaceID.String(), domain.WorkspaceRoleOwner).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (r *WorkspaceMemberRepository) Save(member domain.WorkspaceMember) (domain.WorkspaceMember, error) {
+	model := WorkspaceMemberModel{
+		WorkspaceID: member.WorkspaceID().String(),
+		UserID:      member.UserID().String(),
+		Role:        member.Role().Value(),
+		CreatedAt:   member.JoinedAt().Value(),
+	}
+	// 参加済みの場合は権限のみ更新し、参加日時は保持する
+	if err := r.db.WithContext(r.ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&model).Error; err != nil {
+		return domain.WorkspaceMember{}, err
+	}
+	return r.Find(member.WorkspaceID(), member.UserID())
+}
+
+func (r *WorkspaceMemberRepository) Delete(workspaceID domain.ID, userID domain.ID) error {
+	result := r.db.WithContext(r.ctx).
+		Delete(&WorkspaceMemberModel{}, "workspace_id = ? AND user_id = ?", workspaceID.String(), userID.String())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrEntityNotFound
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package bleveindex はbleveを使ったディスク上の検索インデックスを提供する
+package bleveindex
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/iotassss/gizzmd/internal/domain"
+	"github.com/iotassss/gizzmd/internal/search"
+)
+
+const (
+	fieldTitle   = "title"
+	fieldContent = "content"
+	fieldTags    = "tags"
+	// 検索範囲の絞り込みに使う。解析せずにそのまま登録する
+	fieldWorkspaceID = "workspace_id"
+
+	// 再構築時に1回のバッチで登録する件数
+	rebuildBatchSize = 500
+)
+
+// 検索時のフィールドごとの重み
+type Boosts struct {
+	Title   float64
+	Content float64
+	Tags    float64
+}
+
+// タイトル、タグ、本文の順に重視する
+var DefaultBoosts = Boosts{Title: 3, Content: 1, Tags: 2}
+
+// Indexはdomain.SearchIndexの実装
+// 同じパスのインデックスは1つのプロセスからしか開けない
+type Index struct {
+	index  bleve.Index
+	boosts Boosts
+}
+
+var _ domain.SearchIndex = (*Index)(nil)
+
+// Openはpathのインデックスを開く。存在しない場合は空のインデックスを作成する
+func Open(path string, boosts Boosts) (*Index, error) {
+	index, err := bleve.Open(path)
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		index, err = bleve.New(path, newIndexMapping())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index %s: %w", path, err)
+	}
+	// workspace_idがない古いインデックスでは、範囲を絞り込むと何も一致しなくなる
+	if !hasField(index, fieldWorkspaceID) {
+		index.Close()
+		return nil, fmt.Errorf("search index %s is outdated; rebuild it with `go run ./cmd reindex`", path)
+	}
+	return &Index{index: index, boosts: boosts}, nil
+}
+
+// 日本語は単語で区切られていないため、CJKアナライザーで2文字ずつに分割して登録する
+func newIndexMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = cjk.AnalyzerName
+	text.Store = false
+	text.IncludeInAll = false
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt(fieldTitle, text)
+	doc.AddFieldMappingsAt(fieldContent, text)
+	doc.AddFieldMappingsAt(fieldTags, text)
+	keyword := bleve.NewKeywordFieldMapping()
+	keyword.Store = false
+	keyword.IncludeInAll = false
+	doc.AddFieldMappingsAt(fieldWorkspaceID, keyword)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = doc
+	indexMapping.DefaultAnalyzer = cjk.AnalyzerName
+	return indexMapping
+}
+
+func hasField(index bleve.Index, field string) bool {
+	indexMapping, ok := index.Mapping().(*mapping.IndexMappingImpl)
+	if !ok || indexMapping.DefaultMapping == nil {
+		return false
+	}
+	_, ok = indexMapping.DefaultMapping.Properties[field]
+	return ok
+}
+
+func toIndexDocument(doc domain.Doc) map[string]any {
+	return map[string]any{
+		fieldTitle:       doc.Title().Value(),
+		fieldContent:     doc.Content().Value(),
+		fieldTags:        doc.Tags().Values(),
+		fieldWorkspaceID: doc.WorkspaceID().String(),
+	}
+}
+
+func (i *Index) Index(doc domain.Doc) error {
+	return i.index.Index(doc.ID().String(), toIndexDocument(doc))
+}
+
+func (i *Index) Delete(id domain.ID) error {
+	return i.index.Delete(id.String())
+}
+
+func (i *Index) Search(text domain.SearchText, scope domain.SearchScope, limit int) ([]domain.SearchHit, error) {
+	q := i.buildQuery(text)
+	if q == nil || (scope.IsDocs() && len(scope.DocIDs()) == 0) {
+		return []domain.SearchHit{}, nil
+	}
+	q = bleve.NewConjunctionQuery(q, scopeQuery(scope))
+
+	result, err := i.index.Search(bleve.NewSearchRequestOptions(q, limit, 0, false))
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]domain.SearchHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		id, err := domain.NewID(hit.ID)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, domain.NewSearchHit(id, hit.Score))
+	}
+	return hits, nil
+}
+
+// 検索文字列のすべての条件を満たすものに一致するクエリを返す
+// 各条件はタイトル・本文・タグのいずれかに一致すればよく、フィールドの重みで関連度を変える
+func (i *Index) buildQuery(text domain.SearchText) query.Query {
+	clauses := search.ParseClauses(text.Value())
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	fields := []struct {
+		name  string
+		boost float64
+	}{
+		{fieldTitle, i.boosts.Title},
+		{fieldContent, i.boosts.Content},
+		{fieldTags, i.boosts.Tags},
+	}
+
+	conjuncts := make([]query.Query, len(clauses))
+	for n, clause := range clauses {
+		disjuncts := make([]query.Query, len(fields))
+		for m, field := range fields {
+			disjuncts[m] = clauseQuery(clause, field.name, field.boost)
+		}
+		conjuncts[n] = bleve.NewDisjunctionQuery(disjuncts...)
+	}
+	return bleve.NewConjunctionQuery(conjuncts...)
+}
+
+// 範囲の絞り込みは関連度に影響させない
+func scopeQuery(scope domain.SearchScope) query.Query {
+	if scope.IsDocs() {
+		ids := make([]string, len(scope.DocIDs()))
+		for n, id := range scope.DocIDs() {
+			ids[n] = id.String()
+		}
+		q := bleve.NewDocIDQuery(ids)
+		q.SetBoost(0)
+		return q
+	}
+	q := bleve.NewTermQuery(scope.WorkspaceID().String())
+	q.SetField(fieldWorkspaceID)
+	q.SetBoost(0)
+	return q
+}
+
+func clauseQuery(clause search.Clause, field string, boost float64) query.Query {
+	switch clause.Kind {
+	case search.ClausePhrase:
+		q := bleve.NewMatchPhraseQuery(clause.Text)
+		q.SetField(field)
+		q.SetBoost(boost)
+		return q
+	case search.ClausePrefix:
+		// 前方一致は解析されないため、登録時のアナライザーに合わせて小文字にする
+		q := bleve.NewPrefixQuery(strings.ToLower(clause.Text))
+		q.SetField(field)
+		q.SetBoost(boost)
+		return q
+	default:
+		q := bleve.NewMatchQuery(clause.Text)
+		q.SetField(field)
+		q.SetBoost(boost)
+		q.SetOperator(query.MatchQueryOperatorAnd)
+		return q
+	}
+}
+
+func (i *Index) Close() error {
+	return i.index.Close()
+}
+
+// Rebuildはdocsのドキュメントからpathのインデックスを作り直す
+// 別のパスに作成してから置き換えるため、途中で失敗しても既存のインデックスは残る
+func Rebuild(path string, boosts Boosts, docs domain.DocRepository) (int, error) {
+	tmpPath := path + ".rebuild"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return 0, err
+	}
+	index, err := bleve.New(tmpPath, newIndexMapping())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create search index %s: %w", tmpPath, err)
+	}
+
+	count := 0
+	err = docs.EachBatch(rebuildBatchSize, func(batch []domain.Doc) error {
+		b := index.NewBatch()
+		for _, doc := range batch {
+			if err := b.Index(doc.ID().String(), toIndexDocument(doc)); err != nil {
+				return err
+			}
+		}
+		if err := index.Batch(b); err != nil {
+			return err
+		}
+		count += len(batch)
+		return nil
+	})
+	if closeErr := index.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(tmpPath)
+		return 0, err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,128 @@
+// Package search はドキュメント検索の結果表示に関する処理を提供する
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// 一致箇所の前後に含める文字数
+	fragmentContext = 40
+	// 1つの断片の最大文字数。これを超える場合は一致箇所ごとに断片を分ける
+	maxFragmentLength = 160
+)
+
+type span struct {
+	start, end int
+}
+
+// Highlightはtextのうちtermsに一致する箇所を含む断片を、最大maxFragments件返す
+// 断片はHTMLエスケープし、一致箇所を<mark>で囲む。大文字・小文字は区別しない
+func Highlight(text string, terms []string, maxFragments int) []string {
+	// 断片を1行で表示できるよう、改行などの空白は文字数を変えずに半角スペースに置き換える
+	runes := []rune(text)
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			runes[i] = ' '
+		}
+	}
+	spans := matchSpans(runes, terms)
+	if len(spans) == 0 || maxFragments <= 0 {
+		return nil
+	}
+
+	var fragments []string
+	for i := 0; i < len(spans) && len(fragments) < maxFragments; {
+		start := max(spans[i].start-fragmentContext, 0)
+		end := min(spans[i].end+fragmentContext, len(runes))
+		first := i
+		i++
+		for i < len(spans) && spans[i].end-start <= maxFragmentLength {
+			end = min(max(end, spans[i].end+fragmentContext), start+maxFragmentLength, len(runes))
+			i++
+		}
+		fragments = append(fragments, renderFragment(runes, start, end, spans[first:i]))
+	}
+	return fragments
+}
+
+// 一致箇所を出現順に返す。重なる一致箇所は1つにまとめる
+func matchSpans(runes []rune, terms []string) []span {
+	folded := foldRunes(runes)
+
+	var spans []span
+	for _, term := range terms {
+		needle := foldRunes([]rune(term))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(folded); i++ {
+			if equalRunes(folded[i:i+len(needle)], needle) {
+				spans = append(spans, span{i, i + len(needle)})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.start <= last.end {
+			last.end = max(last.end, s.end)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+func renderFragment(runes []rune, start, end int, spans []span) string {
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, s := range spans {
+		if s.start >= end {
+			break
+		}
+		b.WriteString(escapeFragment(runes[pos:s.start]))
+		b.WriteString("<mark>")
+		b.WriteString(escapeFragment(runes[s.start:min(s.end, end)]))
+		b.WriteString("</mark>")
+		pos = min(s.end, end)
+	}
+	b.WriteString(escapeFragment(runes[pos:end]))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func escapeFragment(runes []rune) string {
+	return html.EscapeString(string(runes))
+}
+
+// 文字数を変えずに小文字に揃える
+func foldRunes(runes []rune) []rune {
+	folded := make([]rune, len(runes))
+	for i, r := range runes {
+		folded[i] = unicode.ToLower(r)
+	}
+	return folded
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+type ClauseKind int
+
+const (
+	// 単語。検索インデックスでは解析後のすべての語を含むものに一致する
+	ClauseTerm ClauseKind = iota
+	// "..."で囲んだフレーズ。語が連続して現れるものに一致する
+	ClausePhrase
+	// 末尾に*を付けた前方一致
+	ClausePrefix
+)
+
+// 検索文字列を構成する条件
+type Clause struct {
+	Kind ClauseKind
+	Text string
+}
+
+// ParseClausesは検索文字列を条件に分解する
+// 閉じていない"は、行末までをフレーズとして扱う
+func ParseClauses(text string) []Clause {
+	var clauses []Clause
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if phrase := strings.Join(strings.Fields(string(runes[i+1:end])), " "); phrase != "" {
+				clauses = append(clauses, Clause{Kind: ClausePhrase, Text: phrase})
+			}
+			i = end + 1
+			continue
+		}
+
+		end := i
+		for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+			end++
+		}
+		word := string(runes[i:end])
+		i = end
+		if prefix := strings.TrimRight(word, "*"); prefix != word {
+			if prefix != "" {
+				clauses = append(clauses, Clause{Kind: ClausePrefix, Text: prefix})
+			}
+			continue
+		}
+		clauses = append(clauses, Clause{Kind: ClauseTerm, Text: word})
+	}
+	return clauses
+}
+
+// HighlightTermsは検索文字列のうち、一致箇所として強調する文字列を返す
+func HighlightTerms(text string) []string {
+	clauses := ParseClauses(text)
+	terms := make([]string, len(clauses))
+	for i, clause := range clauses {
+		terms[i] = clause.Text
+	}
+	return terms
+}
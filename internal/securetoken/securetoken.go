@@ -0,0 +1,34 @@
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const defaultByteLength = 32
+
+// Generateはランダムなトークンと、その保存用ハッシュを生成する
+// 平文のトークンは利用者にのみ渡し、DBにはハッシュのみを保存する
+func Generate() (token string, hash string, err error) {
+	return GenerateWithPrefix("")
+}
+
+// GenerateWithPrefixは種別を判別できるよう接頭辞付きのトークンを生成する
+func GenerateWithPrefix(prefix string) (token string, hash string, err error) {
+	b := make([]byte, defaultByteLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = prefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hashはトークンの検索用ハッシュ（SHA-256の16進表記）を返す
+// トークン自体が十分なエントロピーを持つため、ソルトやストレッチングは不要
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
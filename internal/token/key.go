@@ -0,0 +1,180 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	minHMACSecretBytes = 32
+)
+
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// Keyはkidで識別される署名鍵
+// 公開鍵のみを持つ鍵はローテーション済みの旧鍵として検証にのみ使用する
+type Key struct {
+	id        string
+	algorithm string
+	signKey   any // []byte, *rsa.PrivateKey, ed25519.PrivateKey。検証専用の場合はnil
+	verifyKey any // []byte, *rsa.PublicKey, ed25519.PublicKey
+}
+
+func (k *Key) ID() string        { return k.id }
+func (k *Key) Algorithm() string { return k.algorithm }
+func (k *Key) CanSign() bool     { return k.signKey != nil }
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.algorithm)
+}
+
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) < minHMACSecretBytes {
+		return nil, fmt.Errorf("key %s: HS256 secret must be at least %d bytes", id, minHMACSecretBytes)
+	}
+	return &Key{id: id, algorithm: AlgHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// GenerateEd25519Keyは開発環境向けに一時的な鍵を生成する
+// プロセスの再起動で失われるため、発行済みのトークンは検証できなくなる
+func GenerateEd25519Key(id string) (*Key, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{id: id, algorithm: AlgEdDSA, signKey: priv, verifyKey: pub}, nil
+}
+
+// LoadKeyFileはファイルから鍵を読み込む
+// HS256はファイルの内容をそのまま共有鍵とし、RS256/EdDSAはPEM形式の秘密鍵または公開鍵を受け付ける
+func LoadKeyFile(id, algorithm, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+
+	if algorithm == AlgHS256 {
+		return NewHMACKey(id, []byte(strings.TrimSpace(string(data))))
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM block found in %s", id, path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %s: unsupported PEM block type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+
+	key := &Key{id: id, algorithm: algorithm}
+	switch algorithm {
+	case AlgRS256:
+		switch k := parsed.(type) {
+		case *rsa.PrivateKey:
+			key.signKey, key.verifyKey = k, &k.PublicKey
+		case *rsa.PublicKey:
+			key.verifyKey = k
+		default:
+			return nil, fmt.Errorf("key %s: %s requires an RSA key", id, algorithm)
+		}
+	case AlgEdDSA:
+		switch k := parsed.(type) {
+		case ed25519.PrivateKey:
+			key.signKey, key.verifyKey = k, k.Public()
+		case ed25519.PublicKey:
+			key.verifyKey = k
+		default:
+			return nil, fmt.Errorf("key %s: %s requires an Ed25519 key", id, algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("key %s: unsupported algorithm %s", id, algorithm)
+	}
+	return key, nil
+}
+
+// KeySetは検証に使用する全ての鍵と、新規発行に使用する鍵を保持する
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+func NewKeySet(signingKeyID string, keys ...*Key) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		if _, exists := set.keys[k.id]; exists {
+			return nil, fmt.Errorf("duplicate key id: %s", k.id)
+		}
+		set.keys[k.id] = k
+	}
+
+	signing, ok := set.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q: %w", signingKeyID, ErrKeyNotFound)
+	}
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyID)
+	}
+	set.signing = signing
+	return set, nil
+}
+
+// ParseKeySpecsは "kid:alg:path" をカンマ区切りで並べた設定から鍵を読み込む
+// 例: "2025-10:EdDSA:/run/secrets/jwt-2025-10.pem,2025-04:RS256:/run/secrets/jwt-2025-04.pub.pem"
+func ParseKeySpecs(specs string) ([]*Key, error) {
+	var keys []*Key
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.SplitN(spec, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key spec %q: expected kid:alg:path", spec)
+		}
+		key, err := LoadKeyFile(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys configured")
+	}
+	return keys, nil
+}
+
+func (s *KeySet) Signing() *Key { return s.signing }
+
+func (s *KeySet) Find(id string) (*Key, error) {
+	k, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return k, nil
+}
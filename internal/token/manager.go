@@ -0,0 +1,96 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 時刻のずれを許容する幅
+const leeway = 30 * time.Second
+
+type Config struct {
+	Issuer   string
+	Audience string
+}
+
+// ManagerはKeySetを用いてJWTの署名と検証を行う
+type Manager struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+}
+
+func NewManager(keys *KeySet, config Config) *Manager {
+	return &Manager{
+		keys:     keys,
+		issuer:   config.Issuer,
+		audience: config.Audience,
+	}
+}
+
+// Signは登録済みクレーム（iss, aud, iat, nbf, exp）を付与して署名する
+func (m *Manager) Sign(claims jwt.MapClaims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	signed := jwt.MapClaims{}
+	for k, v := range claims {
+		signed[k] = v
+	}
+	signed["iss"] = m.issuer
+	signed["aud"] = m.audience
+	signed["iat"] = now.Unix()
+	signed["nbf"] = now.Unix()
+	signed["exp"] = expiresAt.Unix()
+
+	key := m.keys.Signing()
+	token := jwt.NewWithClaims(key.method(), signed)
+	token.Header["kid"] = key.ID()
+	s, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return s, expiresAt, nil
+}
+
+// Parseは署名と登録済みクレームを検証し、クレームを返す
+// ヘッダーのalgはkidに対応する鍵のアルゴリズムと一致しなければならない
+func (m *Manager) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("missing kid header: %w", jwt.ErrTokenUnverifiable)
+		}
+		key, err := m.keys.Find(kid)
+		if err != nil {
+			return nil, fmt.Errorf("unknown kid %q: %w", kid, jwt.ErrTokenUnverifiable)
+		}
+		if t.Method.Alg() != key.Algorithm() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.verifyKey, nil
+	},
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithAudience(m.audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// iatとnbfは存在する場合しか検証されないため、必須であることを別途確認する
+	if iat, err := claims.GetIssuedAt(); err != nil || iat == nil {
+		return nil, errors.Join(jwt.ErrTokenInvalidClaims, errors.New("iat claim is required"))
+	}
+	if nbf, err := claims.GetNotBefore(); err != nil || nbf == nil {
+		return nil, errors.Join(jwt.ErrTokenInvalidClaims, errors.New("nbf claim is required"))
+	}
+	return claims, nil
+}
@@ -0,0 +1,48 @@
+package totp
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// 紛らわしい文字（0/o、1/l/i）を除いた英数字
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// 16文字（約80ビット）あれば、SHA-256のハッシュのみで保存しても総当たりは現実的でない
+const recoveryCodeLength = 16
+
+// GenerateRecoveryCodesはn個のリカバリーコードを "xxxx-xxxx-xxxx-xxxx" 形式で生成する
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	buf := make([]byte, recoveryCodeLength)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		var b strings.Builder
+		for j, v := range buf {
+			if j > 0 && j%4 == 0 {
+				b.WriteByte('-')
+			}
+			// 256は31の倍数ではないため僅かに偏るが、エントロピーへの影響は無視できる
+			b.WriteByte(recoveryAlphabet[int(v)%len(recoveryAlphabet)])
+		}
+		codes[i] = b.String()
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCodeは入力揺れ（大文字、ハイフン、空白）を吸収した照合用の文字列を返す
+func NormalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t':
+			return -1
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,64 @@
+// Package trash はゴミ箱に移動したドキュメントを保持期間の経過後に完全に削除する
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/iotassss/gizzmd/internal/repository/gormrepo"
+	"gorm.io/gorm"
+)
+
+// Purgerは一定間隔で保持期間を過ぎたドキュメントを削除する
+type Purger struct {
+	db        *gorm.DB
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(db *gorm.DB, retention, interval time.Duration) *Purger {
+	return &Purger{
+		db:        db,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Runは起動直後と、以降interval毎に削除を実行する。ctxがキャンセルされるまで戻らない
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.PurgeExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpiredは保持期間を過ぎたドキュメントをすべて削除し、削除した件数を返す
+func (p *Purger) PurgeExpired(ctx context.Context) int {
+	docRepo := gormrepo.NewDocRepository(p.db, ctx)
+	before := time.Now().Add(-p.retention)
+
+	total := 0
+	for ctx.Err() == nil {
+		purged, err := docRepo.PurgeTrashedBefore(before)
+		if err != nil {
+			slog.Error("failed to purge trashed documents", slog.Any("error", err))
+			break
+		}
+		total += purged
+		if purged == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		slog.Info("purged trashed documents", slog.Int("count", total))
+	}
+	return total
+}
@@ -0,0 +1,188 @@
+// Package wikilink はMarkdown中のドキュメント間リンクを扱う
+//
+//	[[API設計ガイドライン]]        タイトルでリンクする
+//	[[API設計ガイドライン|設計]]   |より後は表示名
+//	[[doc:55555555-5555-5555-5555-555555555555]] IDでリンクする
+//
+// コードブロック・インラインコード中の[[...]]はリンクとして扱わない
+package wikilink
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/iotassss/gizzmd/internal/domain"
+)
+
+const idPrefix = "doc:"
+
+// タイトルの最大文字数。domain.DocTitleと合わせる
+const maxTargetLength = 100
+
+type Link struct {
+	// リンク先の指定。タイトル、またはdoc:に続くドキュメントID
+	Target string
+	// 表示名。指定しない場合は空
+	Label string
+	// 元の文字列における[[から]]までのバイト位置
+	Start int
+	End   int
+}
+
+// DocIDはdoc:形式のリンクのドキュメントIDを返す。タイトルでのリンクの場合はfalse
+func (l Link) DocID() (domain.ID, bool) {
+	value, ok := strings.CutPrefix(l.Target, idPrefix)
+	if !ok {
+		return domain.ID{}, false
+	}
+	id, err := domain.NewID(value)
+	if err != nil {
+		return domain.ID{}, false
+	}
+	return id, true
+}
+
+// Parseは本文中のリンクを出現順に返す
+func Parse(markdown string) []Link {
+	var links []Link
+	var fence string
+	offset := 0
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		if marker := fenceMarker(line); marker != "" {
+			if fence == "" {
+				fence = marker
+				continue
+			}
+			if marker[0] == fence[0] && len(marker) >= len(fence) && strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), marker[:1])) == "" {
+				fence = ""
+				continue
+			}
+		}
+		if fence != "" {
+			continue
+		}
+		links = append(links, parseLine(line, lineStart)...)
+	}
+	return links
+}
+
+// コードフェンスの開始・終了行の場合は```や~~~の部分を返す
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+	if c := trimmed[0]; c == '`' || c == '~' {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == c {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+func parseLine(line string, lineStart int) []Link {
+	var links []Link
+	for i := 0; i < len(line); {
+		// インラインコードは同じ数の`で閉じるまで読み飛ばす
+		if line[i] == '`' {
+			n := 0
+			for i+n < len(line) && line[i+n] == '`' {
+				n++
+			}
+			if end := closingBackticks(line, i+n, n); end >= 0 {
+				i = end + n
+				continue
+			}
+			i += n
+			continue
+		}
+
+		if !strings.HasPrefix(line[i:], "[[") {
+			i++
+			continue
+		}
+		end := strings.Index(line[i+2:], "]]")
+		if end < 0 {
+			break
+		}
+		inner := line[i+2 : i+2+end]
+		if strings.ContainsAny(inner, "[]`") {
+			i++
+			continue
+		}
+		target, label, _ := strings.Cut(inner, "|")
+		target = strings.TrimSpace(target)
+		if target == "" || utf8.RuneCountInString(target) > maxTargetLength {
+			i += 2 + end + 2
+			continue
+		}
+		links = append(links, Link{
+			Target: target,
+			Label:  strings.TrimSpace(label),
+			Start:  lineStart + i,
+			End:    lineStart + i + 2 + end + 2,
+		})
+		i += 2 + end + 2
+	}
+	return links
+}
+
+// fromから探し、ちょうどn個連続する`の位置を返す。ない場合は-1
+func closingBackticks(line string, from, n int) int {
+	for i := from; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		m := 0
+		for i+m < len(line) && line[i+m] == '`' {
+			m++
+		}
+		if m == n {
+			return i
+		}
+		i += m
+	}
+	return -1
+}
+
+// IsLinkableTitleはtitleを[[title]]と書いてリンクできるかを返す
+// |・[・]・`・改行を含むタイトルは、表示名の区切りやリンクの終わりと解釈されるため書けない
+func IsLinkableTitle(title string) bool {
+	return title != "" && strings.TrimSpace(title) == title && !strings.HasPrefix(title, idPrefix) && !strings.ContainsAny(title, "|[]`\r\n")
+}
+
+// RenameTitleはoldTitleへのタイトルでのリンクをnewTitleへのリンクに書き換える
+// 表示名は保持する。書き換えた場合はtrueを返す。newTitleがリンクに書けない場合は書き換えない
+func RenameTitle(markdown, oldTitle, newTitle string) (string, bool) {
+	if !IsLinkableTitle(newTitle) {
+		return markdown, false
+	}
+	links := Parse(markdown)
+	var b strings.Builder
+	last := 0
+	for _, link := range links {
+		if link.Target != oldTitle {
+			continue
+		}
+		b.WriteString(markdown[last:link.Start])
+		b.WriteString("[[" + newTitle)
+		if link.Label != "" {
+			b.WriteString("|" + link.Label)
+		}
+		b.WriteString("]]")
+		last = link.End
+	}
+	if last == 0 {
+		return markdown, false
+	}
+	b.WriteString(markdown[last:])
+	return b.String(), true
+}